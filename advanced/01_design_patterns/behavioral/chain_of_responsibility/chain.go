@@ -0,0 +1,151 @@
+package chain_of_responsibility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnhandled is returned when every handler in a chain passed on the request
+var ErrUnhandled = errors.New("chain: request not handled")
+
+// ContextHandler is one link in a Chain. Handle reports handled as false to
+// pass the request on to the next handler; a non-nil error stops the chain.
+type ContextHandler[Req, Resp any] interface {
+    Name() string
+    Handle(ctx context.Context, request Req) (response Resp, handled bool, err error)
+}
+
+// HandlerFunc adapts a function to a named ContextHandler
+type HandlerFunc[Req, Resp any] func(ctx context.Context, request Req) (Resp, bool, error)
+
+type namedHandler[Req, Resp any] struct {
+    name string
+    fn   HandlerFunc[Req, Resp]
+}
+
+func (h namedHandler[Req, Resp]) Name() string {
+    return h.name
+}
+
+func (h namedHandler[Req, Resp]) Handle(ctx context.Context, request Req) (Resp, bool, error) {
+    return h.fn(ctx, request)
+}
+
+// NewHandler creates a ContextHandler called name from fn
+func NewHandler[Req, Resp any](name string, fn HandlerFunc[Req, Resp]) ContextHandler[Req, Resp] {
+    return namedHandler[Req, Resp]{name: name, fn: fn}
+}
+
+// Outcome is what one handler did with a request
+type Outcome int
+
+const (
+    // Passed means the handler saw the request and passed it on
+    Passed Outcome = iota
+    // Handled means the handler produced the response
+    Handled
+    // Failed means the handler returned an error
+    Failed
+    // Cancelled means a parallel handler was still running when the chain finished
+    Cancelled
+)
+
+func (o Outcome) String() string {
+    switch o {
+    case Passed:
+        return "passed"
+    case Handled:
+        return "handled"
+    case Failed:
+        return "failed"
+    case Cancelled:
+        return "cancelled"
+    default:
+        return fmt.Sprintf("Outcome(%d)", int(o))
+    }
+}
+
+// Step records one handler seeing a request
+type Step struct {
+    Handler  string
+    Outcome  Outcome
+    Duration time.Duration
+    Err      error
+}
+
+// Trace lists the handlers that saw a request, in chain order. Handlers after
+// the one that handled or failed the request do not appear.
+type Trace []Step
+
+// String formats the trace as e.g. "auth:passed -> cache:handled"
+func (t Trace) String() string {
+    parts := make([]string, len(t))
+    for i, step := range t {
+        parts[i] = step.Handler + ":" + step.Outcome.String()
+    }
+    return strings.Join(parts, " -> ")
+}
+
+// HandlerError is returned when a handler fails
+type HandlerError struct {
+    Handler string
+    Err     error
+}
+
+func (e *HandlerError) Error() string {
+    return fmt.Sprintf("chain: handler %s: %v", e.Handler, e.Err)
+}
+
+func (e *HandlerError) Unwrap() error {
+    return e.Err
+}
+
+// Chain passes typed requests along its handlers in order until one handles it
+type Chain[Req, Resp any] struct {
+    handlers []ContextHandler[Req, Resp]
+}
+
+// NewChain creates a new Chain of handlers
+func NewChain[Req, Resp any](handlers ...ContextHandler[Req, Resp]) *Chain[Req, Resp] {
+    return &Chain[Req, Resp]{handlers: handlers}
+}
+
+// Append adds handlers to the end of the chain and returns the chain
+func (c *Chain[Req, Resp]) Append(handlers ...ContextHandler[Req, Resp]) *Chain[Req, Resp] {
+    c.handlers = append(c.handlers, handlers...)
+    return c
+}
+
+// Handle sends request along the chain. It returns the first handler's
+// response, a *HandlerError if a handler fails, ErrUnhandled if every handler
+// passes, or ctx.Err() if ctx ends before a handler is reached. The trace is
+// returned in every case.
+func (c *Chain[Req, Resp]) Handle(ctx context.Context, request Req) (Resp, Trace, error) {
+    var zero Resp
+    trace := make(Trace, 0, len(c.handlers))
+    for _, handler := range c.handlers {
+        if err := ctx.Err(); err != nil {
+            return zero, trace, err
+        }
+        start := time.Now()
+        response, handled, err := handler.Handle(ctx, request)
+        step := Step{Handler: handler.Name(), Duration: time.Since(start)}
+        switch {
+        case err != nil:
+            step.Outcome, step.Err = Failed, err
+            trace = append(trace, step)
+            return zero, trace, &HandlerError{Handler: step.Handler, Err: err}
+        case handled:
+            step.Outcome = Handled
+            trace = append(trace, step)
+            return response, trace, nil
+        default:
+            step.Outcome = Passed
+            trace = append(trace, step)
+        }
+    }
+    return zero, trace, ErrUnhandled
+}
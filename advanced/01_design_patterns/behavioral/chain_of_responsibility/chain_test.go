@@ -0,0 +1,121 @@
+package chain_of_responsibility
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type order struct {
+    Item  string
+    Total int
+}
+
+func letterHandler(letter string) ContextHandler[string, string] {
+    return NewHandler(letter, func(ctx context.Context, request string) (string, bool, error) {
+        if request != letter {
+            return "", false, nil
+        }
+        return "handler " + letter + " handled the request", true, nil
+    })
+}
+
+func TestChainHandle(t *testing.T) {
+    chain := NewChain(letterHandler("A"), letterHandler("B"), letterHandler("C"))
+
+    response, trace, err := chain.Handle(context.Background(), "B")
+    if err != nil || response != "handler B handled the request" {
+        t.Fatalf("Unexpected result '%s', %v", response, err)
+    }
+    if trace.String() != "A:passed -> B:handled" {
+        t.Errorf("Unexpected trace %s", trace)
+    }
+}
+
+func TestChainUnhandled(t *testing.T) {
+    chain := NewChain(letterHandler("A"), letterHandler("B"))
+    _, trace, err := chain.Handle(context.Background(), "D")
+    if !errors.Is(err, ErrUnhandled) {
+        t.Fatalf("Expected ErrUnhandled, got %v", err)
+    }
+    if trace.String() != "A:passed -> B:passed" {
+        t.Errorf("Unexpected trace %s", trace)
+    }
+
+    if _, _, err := NewChain[string, string]().Handle(context.Background(), "A"); !errors.Is(err, ErrUnhandled) {
+        t.Errorf("Expected an empty chain to leave the request unhandled, got %v", err)
+    }
+}
+
+func TestChainEmptyResponseIsHandled(t *testing.T) {
+    // Unlike Handler.Handle, an empty response is distinguishable from no handler
+    empty := NewHandler("empty", func(ctx context.Context, request string) (string, bool, error) {
+        return "", true, nil
+    })
+    response, _, err := NewChain(empty).Handle(context.Background(), "anything")
+    if err != nil || response != "" {
+        t.Errorf("Expected an empty handled response, got '%s', %v", response, err)
+    }
+}
+
+func TestChainHandlerError(t *testing.T) {
+    errLimit := errors.New("over limit")
+    var reachedLast bool
+    chain := NewChain(
+        NewHandler("validate", func(ctx context.Context, o order) (int, bool, error) {
+            return 0, false, nil
+        }),
+        NewHandler("limit", func(ctx context.Context, o order) (int, bool, error) {
+            if o.Total > 100 {
+                return 0, false, errLimit
+            }
+            return 0, false, nil
+        }),
+        NewHandler("approve", func(ctx context.Context, o order) (int, bool, error) {
+            reachedLast = true
+            return o.Total, true, nil
+        }),
+    )
+
+    _, trace, err := chain.Handle(context.Background(), order{Item: "tv", Total: 500})
+    var handlerErr *HandlerError
+    if !errors.As(err, &handlerErr) || handlerErr.Handler != "limit" || !errors.Is(err, errLimit) {
+        t.Fatalf("Expected a HandlerError from limit, got %v", err)
+    }
+    if reachedLast {
+        t.Error("Expected the chain to stop at the failing handler")
+    }
+    if trace.String() != "validate:passed -> limit:failed" || trace[1].Err != errLimit {
+        t.Errorf("Unexpected trace %s", trace)
+    }
+
+    total, _, err := chain.Handle(context.Background(), order{Item: "pen", Total: 3})
+    if err != nil || total != 3 {
+        t.Errorf("Expected approval, got %d, %v", total, err)
+    }
+}
+
+func TestChainContextCancellation(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    chain := NewChain(
+        NewHandler("cancel", func(ctx context.Context, request string) (string, bool, error) {
+            cancel()
+            return "", false, nil
+        }),
+        letterHandler("A"),
+    )
+    _, trace, err := chain.Handle(ctx, "A")
+    if !errors.Is(err, context.Canceled) {
+        t.Fatalf("Expected context.Canceled, got %v", err)
+    }
+    if trace.String() != "cancel:passed" {
+        t.Errorf("Expected the chain to stop before A, got %s", trace)
+    }
+}
+
+func TestChainAppend(t *testing.T) {
+    chain := NewChain(letterHandler("A")).Append(letterHandler("B"))
+    if response, _, err := chain.Handle(context.Background(), "B"); err != nil || response != "handler B handled the request" {
+        t.Errorf("Unexpected result '%s', %v", response, err)
+    }
+}
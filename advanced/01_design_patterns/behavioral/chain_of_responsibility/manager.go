@@ -0,0 +1,286 @@
+package chain_of_responsibility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrHandlerExists is returned when a manager already has a handler with the given name
+var ErrHandlerExists = errors.New("chain: handler already exists")
+
+// ErrHandlerNotFound is returned when a manager has no handler with the given name
+var ErrHandlerNotFound = errors.New("chain: handler not found")
+
+// ErrUnknownHandlerType is returned when a config names a handler type missing from the registry
+var ErrUnknownHandlerType = errors.New("chain: unknown handler type")
+
+// ErrUnknownParam is returned when a handler config has a param its factory does not take
+var ErrUnknownParam = errors.New("chain: unknown param")
+
+// ErrMissingParam is returned when a handler config leaves out a required param
+var ErrMissingParam = errors.New("chain: missing param")
+
+// HandlerInfo describes one handler held by a ChainManager
+type HandlerInfo struct {
+    Name     string
+    Priority int
+}
+
+type managedHandler[Req, Resp any] struct {
+    handler  ContextHandler[Req, Resp]
+    priority int
+    // seq keeps insertion order among handlers with the same priority
+    seq int
+}
+
+// ChainManager holds handlers by name and priority and can be changed while
+// requests are in flight. Handlers with lower priority values run first.
+// Every change publishes a new immutable Chain, so a request always runs
+// against one consistent snapshot and never waits on a writer.
+type ChainManager[Req, Resp any] struct {
+    mu      sync.Mutex
+    entries []managedHandler[Req, Resp]
+    nextSeq int
+    chain   atomic.Pointer[Chain[Req, Resp]]
+}
+
+// NewChainManager creates a new, empty ChainManager
+func NewChainManager[Req, Resp any]() *ChainManager[Req, Resp] {
+    m := &ChainManager[Req, Resp]{}
+    m.chain.Store(NewChain[Req, Resp]())
+    return m
+}
+
+// Handle sends request along the current snapshot of the chain
+func (m *ChainManager[Req, Resp]) Handle(ctx context.Context, request Req) (Resp, Trace, error) {
+    return m.chain.Load().Handle(ctx, request)
+}
+
+// Broadcast sends request to every handler of the current snapshot; see Chain.Broadcast
+func (m *ChainManager[Req, Resp]) Broadcast(ctx context.Context, request Req) ([]Resp, Trace, error) {
+    return m.chain.Load().Broadcast(ctx, request)
+}
+
+// Race runs the current snapshot's handlers in parallel; see Chain.Race
+func (m *ChainManager[Req, Resp]) Race(ctx context.Context, request Req) (Resp, Trace, error) {
+    return m.chain.Load().Race(ctx, request)
+}
+
+// Quorum runs the current snapshot's handlers in parallel until n handle request; see Chain.Quorum
+func (m *ChainManager[Req, Resp]) Quorum(ctx context.Context, request Req, n int) ([]Resp, Trace, error) {
+    return m.chain.Load().Quorum(ctx, request, n)
+}
+
+// Add inserts a handler at the given priority
+func (m *ChainManager[Req, Resp]) Add(handler ContextHandler[Req, Resp], priority int) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if m.index(handler.Name()) >= 0 {
+        return fmt.Errorf("%w: %q", ErrHandlerExists, handler.Name())
+    }
+    m.entries = append(m.entries, managedHandler[Req, Resp]{handler: handler, priority: priority, seq: m.nextSeq})
+    m.nextSeq++
+    m.publish()
+    return nil
+}
+
+// Remove deletes the named handler
+func (m *ChainManager[Req, Resp]) Remove(name string) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    i := m.index(name)
+    if i < 0 {
+        return fmt.Errorf("%w: %q", ErrHandlerNotFound, name)
+    }
+    m.entries = slices.Delete(m.entries, i, i+1)
+    m.publish()
+    return nil
+}
+
+// Replace swaps in a new handler for the one with the same name, keeping its place in the chain
+func (m *ChainManager[Req, Resp]) Replace(handler ContextHandler[Req, Resp]) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    i := m.index(handler.Name())
+    if i < 0 {
+        return fmt.Errorf("%w: %q", ErrHandlerNotFound, handler.Name())
+    }
+    m.entries[i].handler = handler
+    m.publish()
+    return nil
+}
+
+// SetPriority moves the named handler to a new priority
+func (m *ChainManager[Req, Resp]) SetPriority(name string, priority int) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    i := m.index(name)
+    if i < 0 {
+        return fmt.Errorf("%w: %q", ErrHandlerNotFound, name)
+    }
+    m.entries[i].priority = priority
+    m.publish()
+    return nil
+}
+
+// Handlers lists the handlers in the order requests visit them
+func (m *ChainManager[Req, Resp]) Handlers() []HandlerInfo {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    infos := make([]HandlerInfo, len(m.entries))
+    for i, entry := range m.entries {
+        infos[i] = HandlerInfo{Name: entry.handler.Name(), Priority: entry.priority}
+    }
+    return infos
+}
+
+// Snapshot returns a copy of the current chain, unaffected by later changes
+func (m *ChainManager[Req, Resp]) Snapshot() *Chain[Req, Resp] {
+    return NewChain(slices.Clone(m.chain.Load().handlers)...)
+}
+
+// LoadConfig replaces every handler with the ones declared in config. If any
+// handler cannot be built the manager keeps its current chain.
+func (m *ChainManager[Req, Resp]) LoadConfig(config ChainConfig, registry *Registry[Req, Resp]) error {
+    if registry == nil {
+        return errors.New("chain: nil registry")
+    }
+    entries := make([]managedHandler[Req, Resp], 0, len(config.Handlers))
+    seen := make(map[string]bool)
+    for i, handlerConfig := range config.Handlers {
+        factory, ok := registry.factories[handlerConfig.Type]
+        if !ok {
+            return fmt.Errorf("%w: %q", ErrUnknownHandlerType, handlerConfig.Type)
+        }
+        name := handlerConfig.Name
+        if name == "" {
+            name = handlerConfig.Type
+        }
+        if seen[name] {
+            return fmt.Errorf("%w: %q", ErrHandlerExists, name)
+        }
+        seen[name] = true
+        handler, err := factory(name, handlerConfig.Params)
+        if err != nil {
+            return fmt.Errorf("chain: handler %q: %w", name, err)
+        }
+        entries = append(entries, managedHandler[Req, Resp]{handler: handler, priority: handlerConfig.Priority, seq: i})
+    }
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.entries = entries
+    m.nextSeq = len(entries)
+    m.publish()
+    return nil
+}
+
+// index returns the position of the named handler, or -1. The caller holds m.mu.
+func (m *ChainManager[Req, Resp]) index(name string) int {
+    for i, entry := range m.entries {
+        if entry.handler.Name() == name {
+            return i
+        }
+    }
+    return -1
+}
+
+// publish sorts the entries and stores a new chain built from them. The caller holds m.mu.
+func (m *ChainManager[Req, Resp]) publish() {
+    sort.Slice(m.entries, func(i, j int) bool {
+        if m.entries[i].priority != m.entries[j].priority {
+            return m.entries[i].priority < m.entries[j].priority
+        }
+        return m.entries[i].seq < m.entries[j].seq
+    })
+    handlers := make([]ContextHandler[Req, Resp], len(m.entries))
+    for i, entry := range m.entries {
+        handlers[i] = entry.handler
+    }
+    m.chain.Store(NewChain(handlers...))
+}
+
+// HandlerConfig declares one handler of a chain
+type HandlerConfig struct {
+    // Name identifies the handler in the chain. Defaults to Type.
+    Name string `json:"name,omitempty"`
+    // Type selects the factory in the registry
+    Type     string            `json:"type"`
+    Priority int               `json:"priority"`
+    Params   map[string]string `json:"params,omitempty"`
+}
+
+// ChainConfig declares the handlers of a chain
+type ChainConfig struct {
+    Handlers []HandlerConfig `json:"handlers"`
+}
+
+// Factory creates a handler called name from its params
+type Factory[Req, Resp any] func(name string, params map[string]string) (ContextHandler[Req, Resp], error)
+
+// Registry maps handler type names to factories
+type Registry[Req, Resp any] struct {
+    factories map[string]Factory[Req, Resp]
+}
+
+// NewRegistry creates a new, empty Registry
+func NewRegistry[Req, Resp any]() *Registry[Req, Resp] {
+    return &Registry[Req, Resp]{factories: make(map[string]Factory[Req, Resp])}
+}
+
+// Register adds a factory under the given type name, replacing any previous one
+func (r *Registry[Req, Resp]) Register(handlerType string, factory Factory[Req, Resp]) {
+    r.factories[handlerType] = factory
+}
+
+// NewStringRegistry returns a registry of string handlers: "match" handles
+// requests equal to param "request" with param "response", and "fallback"
+// handles every request with param "response"
+func NewStringRegistry() *Registry[string, string] {
+    registry := NewRegistry[string, string]()
+    registry.Register("match", func(name string, params map[string]string) (ContextHandler[string, string], error) {
+        if err := checkParams(params, "request", "response"); err != nil {
+            return nil, err
+        }
+        request, ok := params["request"]
+        if !ok {
+            return nil, fmt.Errorf("%w: %q", ErrMissingParam, "request")
+        }
+        response := params["response"]
+        return NewHandler(name, func(ctx context.Context, r string) (string, bool, error) {
+            return response, r == request, nil
+        }), nil
+    })
+    registry.Register("fallback", func(name string, params map[string]string) (ContextHandler[string, string], error) {
+        if err := checkParams(params, "response"); err != nil {
+            return nil, err
+        }
+        response := params["response"]
+        return NewHandler(name, func(ctx context.Context, r string) (string, bool, error) {
+            return response, true, nil
+        }), nil
+    })
+    return registry
+}
+
+// checkParams returns an error naming every param not in known
+func checkParams(params map[string]string, known ...string) error {
+    var unknown []string
+    for key := range params {
+        if !slices.Contains(known, key) {
+            unknown = append(unknown, strconv.Quote(key))
+        }
+    }
+    if len(unknown) > 0 {
+        slices.Sort(unknown)
+        return fmt.Errorf("%w: %s", ErrUnknownParam, strings.Join(unknown, ", "))
+    }
+    return nil
+}
@@ -0,0 +1,192 @@
+package chain_of_responsibility
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestChainManagerPriorityOrder(t *testing.T) {
+    manager := NewChainManager[string, string]()
+    manager.Add(letterHandler("C"), 30)
+    manager.Add(letterHandler("A"), 10)
+    manager.Add(letterHandler("B"), 20)
+    manager.Add(letterHandler("B2"), 20)
+
+    expected := []HandlerInfo{{"A", 10}, {"B", 20}, {"B2", 20}, {"C", 30}}
+    if handlers := manager.Handlers(); !reflect.DeepEqual(handlers, expected) {
+        t.Errorf("Expected %v, got %v", expected, handlers)
+    }
+    _, trace, err := manager.Handle(context.Background(), "C")
+    if err != nil || trace.String() != "A:passed -> B:passed -> B2:passed -> C:handled" {
+        t.Errorf("Unexpected trace %s, %v", trace, err)
+    }
+}
+
+func TestChainManagerChanges(t *testing.T) {
+    manager := NewChainManager[string, string]()
+    manager.Add(letterHandler("A"), 1)
+    manager.Add(letterHandler("B"), 2)
+
+    if err := manager.Add(letterHandler("A"), 3); !errors.Is(err, ErrHandlerExists) {
+        t.Errorf("Expected ErrHandlerExists, got %v", err)
+    }
+    if err := manager.Remove("Z"); !errors.Is(err, ErrHandlerNotFound) {
+        t.Errorf("Expected ErrHandlerNotFound, got %v", err)
+    }
+
+    if err := manager.SetPriority("B", 0); err != nil {
+        t.Fatal(err)
+    }
+    if _, trace, _ := manager.Handle(context.Background(), "A"); trace.String() != "B:passed -> A:handled" {
+        t.Errorf("Expected B to move first, got %s", trace)
+    }
+
+    replacement := NewHandler("A", func(ctx context.Context, request string) (string, bool, error) {
+        return "replaced", true, nil
+    })
+    if err := manager.Replace(replacement); err != nil {
+        t.Fatal(err)
+    }
+    if response, _, _ := manager.Handle(context.Background(), "X"); response != "replaced" {
+        t.Errorf("Expected the replacement to run, got '%s'", response)
+    }
+
+    if err := manager.Remove("A"); err != nil {
+        t.Fatal(err)
+    }
+    if _, _, err := manager.Handle(context.Background(), "X"); !errors.Is(err, ErrUnhandled) {
+        t.Errorf("Expected ErrUnhandled after removing A, got %v", err)
+    }
+}
+
+func TestChainManagerSnapshotIsolation(t *testing.T) {
+    manager := NewChainManager[string, string]()
+    manager.Add(letterHandler("A"), 1)
+    snapshot := manager.Snapshot()
+    manager.Remove("A")
+
+    if _, _, err := snapshot.Handle(context.Background(), "A"); err != nil {
+        t.Errorf("Expected the snapshot to keep A, got %v", err)
+    }
+}
+
+func TestChainManagerInFlightRequests(t *testing.T) {
+    manager := NewChainManager[string, string]()
+    manager.Add(letterHandler("A"), 1)
+    started, release := make(chan struct{}), make(chan struct{})
+    manager.Add(NewHandler("slow", func(ctx context.Context, request string) (string, bool, error) {
+        close(started)
+        <-release
+        return "", false, nil
+    }), 0)
+
+    done := make(chan error)
+    go func() {
+        _, _, err := manager.Handle(context.Background(), "A")
+        done <- err
+    }()
+    <-started
+    // The request in flight keeps the chain it started with
+    manager.Remove("A")
+    close(release)
+    if err := <-done; err != nil {
+        t.Errorf("Expected the in-flight request to reach A, got %v", err)
+    }
+}
+
+func TestChainManagerConcurrentChanges(t *testing.T) {
+    manager := NewChainManager[string, string]()
+    manager.Add(letterHandler("A"), 0)
+
+    var wg sync.WaitGroup
+    for g := 0; g < 4; g++ {
+        wg.Add(2)
+        go func() {
+            defer wg.Done()
+            name := fmt.Sprintf("H%d", g)
+            for i := 0; i < 200; i++ {
+                manager.Add(letterHandler(name), i%5)
+                manager.SetPriority(name, -i)
+                manager.Remove(name)
+            }
+        }()
+        go func() {
+            defer wg.Done()
+            for i := 0; i < 200; i++ {
+                if _, _, err := manager.Handle(context.Background(), "A"); err != nil {
+                    t.Errorf("Expected A to handle every request, got %v", err)
+                    return
+                }
+            }
+        }()
+    }
+    wg.Wait()
+}
+
+func TestChainManagerLoadConfig(t *testing.T) {
+    raw := `{
+        "handlers": [
+            {"type": "fallback", "priority": 100, "params": {"response": "default"}},
+            {"name": "ping", "type": "match", "priority": 1, "params": {"request": "ping", "response": "pong"}}
+        ]
+    }`
+    var config ChainConfig
+    if err := json.Unmarshal([]byte(raw), &config); err != nil {
+        t.Fatal(err)
+    }
+
+    manager := NewChainManager[string, string]()
+    if err := manager.LoadConfig(config, NewStringRegistry()); err != nil {
+        t.Fatalf("LoadConfig failed: %v", err)
+    }
+    expected := []HandlerInfo{{"ping", 1}, {"fallback", 100}}
+    if handlers := manager.Handlers(); !reflect.DeepEqual(handlers, expected) {
+        t.Errorf("Expected %v, got %v", expected, handlers)
+    }
+    if response, _, _ := manager.Handle(context.Background(), "ping"); response != "pong" {
+        t.Errorf("Expected 'pong', got '%s'", response)
+    }
+    if response, _, _ := manager.Handle(context.Background(), "other"); response != "default" {
+        t.Errorf("Expected 'default', got '%s'", response)
+    }
+}
+
+func TestChainManagerLoadConfigErrors(t *testing.T) {
+    tests := []struct {
+        name   string
+        config ChainConfig
+        err    error
+    }{
+        {"unknown type", ChainConfig{Handlers: []HandlerConfig{{Type: "nope"}}}, ErrUnknownHandlerType},
+        {"duplicate name", ChainConfig{Handlers: []HandlerConfig{{Type: "fallback"}, {Type: "fallback"}}}, ErrHandlerExists},
+        {"missing param", ChainConfig{Handlers: []HandlerConfig{{Type: "match"}}}, ErrMissingParam},
+        {"unknown param", ChainConfig{Handlers: []HandlerConfig{{Type: "fallback", Params: map[string]string{"respnse": "x"}}}}, ErrUnknownParam},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            manager := NewChainManager[string, string]()
+            manager.Add(letterHandler("A"), 0)
+            err := manager.LoadConfig(tt.config, NewStringRegistry())
+            if !errors.Is(err, tt.err) {
+                t.Fatalf("Expected %v, got %v", tt.err, err)
+            }
+            // A failed load keeps the current chain
+            if _, _, err := manager.Handle(context.Background(), "A"); err != nil {
+                t.Errorf("Expected the old chain to remain, got %v", err)
+            }
+        })
+    }
+}
+
+func TestChainManagerLoadConfigNilRegistry(t *testing.T) {
+    manager := NewChainManager[string, string]()
+    config := ChainConfig{Handlers: []HandlerConfig{{Type: "fallback"}}}
+    if err := manager.LoadConfig(config, nil); err == nil {
+        t.Error("Expected an error for a nil registry")
+    }
+}
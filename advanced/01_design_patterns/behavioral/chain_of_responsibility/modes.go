@@ -0,0 +1,145 @@
+package chain_of_responsibility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrQuorumNotMet is returned when too few handlers handle a request for Quorum to succeed
+var ErrQuorumNotMet = errors.New("chain: quorum not met")
+
+// Broadcast sends request to every handler in order, as for validators or
+// enrichers. It returns the responses of the handlers that handled it, in
+// chain order, and every handler error joined together; a failing handler
+// does not stop the others. Only ctx ending stops the broadcast early.
+func (c *Chain[Req, Resp]) Broadcast(ctx context.Context, request Req) ([]Resp, Trace, error) {
+    var responses []Resp
+    var errs []error
+    trace := make(Trace, 0, len(c.handlers))
+    for _, handler := range c.handlers {
+        if err := ctx.Err(); err != nil {
+            errs = append(errs, err)
+            break
+        }
+        start := time.Now()
+        response, handled, err := handler.Handle(ctx, request)
+        step := Step{Handler: handler.Name(), Duration: time.Since(start)}
+        switch {
+        case err != nil:
+            step.Outcome, step.Err = Failed, err
+            errs = append(errs, &HandlerError{Handler: step.Handler, Err: err})
+        case handled:
+            step.Outcome = Handled
+            responses = append(responses, response)
+        default:
+            step.Outcome = Passed
+        }
+        trace = append(trace, step)
+    }
+    return responses, trace, errors.Join(errs...)
+}
+
+// Race runs every handler in parallel, as for cache tiers, and returns the
+// first response handled without error. The other handlers are cancelled
+// through their ctx and appear as Cancelled in the trace if still running.
+// If no handler handles the request, the error matches ErrUnhandled and
+// wraps every handler error.
+func (c *Chain[Req, Resp]) Race(ctx context.Context, request Req) (Resp, Trace, error) {
+    var zero Resp
+    responses, trace, errs := c.fanOut(ctx, request, 1)
+    if len(responses) == 1 {
+        return responses[0], trace, nil
+    }
+    if err := ctx.Err(); err != nil {
+        return zero, trace, err
+    }
+    return zero, trace, errors.Join(append([]error{ErrUnhandled}, errs...)...)
+}
+
+// Quorum runs every handler in parallel and succeeds once n of them have
+// handled the request, returning those n responses in chain order and
+// cancelling the rest. It fails with ErrQuorumNotMet, wrapping every handler
+// error, as soon as too few handlers are left to reach n. n below 1 is treated as 1.
+func (c *Chain[Req, Resp]) Quorum(ctx context.Context, request Req, n int) ([]Resp, Trace, error) {
+    n = max(n, 1)
+    responses, trace, errs := c.fanOut(ctx, request, n)
+    if len(responses) == n {
+        return responses, trace, nil
+    }
+    if err := ctx.Err(); err != nil {
+        return nil, trace, err
+    }
+    quorumErr := fmt.Errorf("%w: %d of %d handled", ErrQuorumNotMet, len(responses), n)
+    return nil, trace, errors.Join(append([]error{quorumErr}, errs...)...)
+}
+
+// fanOutResult is what one parallel handler returned
+type fanOutResult[Resp any] struct {
+    index    int
+    step     Step
+    response Resp
+}
+
+// fanOut runs every handler in parallel until need of them have handled the
+// request, it becomes impossible for need to handle it, or ctx ends. It
+// returns the handled responses in chain order and the handler errors.
+// Handlers still running are cancelled and not waited for.
+func (c *Chain[Req, Resp]) fanOut(ctx context.Context, request Req, need int) ([]Resp, Trace, []error) {
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    // Buffered so handlers that finish after fanOut returns do not block
+    results := make(chan fanOutResult[Resp], len(c.handlers))
+    for i, handler := range c.handlers {
+        go func() {
+            start := time.Now()
+            response, handled, err := handler.Handle(ctx, request)
+            step := Step{Handler: handler.Name(), Duration: time.Since(start)}
+            switch {
+            case err != nil:
+                step.Outcome, step.Err = Failed, err
+            case handled:
+                step.Outcome = Handled
+            default:
+                step.Outcome = Passed
+            }
+            results <- fanOutResult[Resp]{index: i, step: step, response: response}
+        }()
+    }
+
+    finished := make([]*fanOutResult[Resp], len(c.handlers))
+    handled, pending := 0, len(c.handlers)
+    var errs []error
+loop:
+    for handled < need && handled+pending >= need {
+        select {
+        case result := <-results:
+            pending--
+            finished[result.index] = &result
+            switch result.step.Outcome {
+            case Handled:
+                handled++
+            case Failed:
+                errs = append(errs, &HandlerError{Handler: result.step.Handler, Err: result.step.Err})
+            }
+        case <-ctx.Done():
+            break loop
+        }
+    }
+
+    var responses []Resp
+    trace := make(Trace, len(c.handlers))
+    for i, result := range finished {
+        if result == nil {
+            trace[i] = Step{Handler: c.handlers[i].Name(), Outcome: Cancelled}
+            continue
+        }
+        trace[i] = result.step
+        if result.step.Outcome == Handled && handled >= need {
+            responses = append(responses, result.response)
+        }
+    }
+    return responses, trace, errs
+}
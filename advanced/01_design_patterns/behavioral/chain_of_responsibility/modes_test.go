@@ -0,0 +1,189 @@
+package chain_of_responsibility
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tierHandler answers after delay, or returns err, and reports whether it was cancelled
+func tierHandler(name string, delay time.Duration, handled bool, err error, cancelled chan<- string) ContextHandler[string, string] {
+    return NewHandler(name, func(ctx context.Context, request string) (string, bool, error) {
+        select {
+        case <-time.After(delay):
+            return name + ":" + request, handled, err
+        case <-ctx.Done():
+            if cancelled != nil {
+                cancelled <- name
+            }
+            return "", false, ctx.Err()
+        }
+    })
+}
+
+func TestChainBroadcast(t *testing.T) {
+    errShort := errors.New("too short")
+    errDigits := errors.New("no digits")
+    chain := NewChain(
+        NewHandler("length", func(ctx context.Context, password string) (string, bool, error) {
+            if len(password) < 8 {
+                return "", false, errShort
+            }
+            return "", false, nil
+        }),
+        NewHandler("digits", func(ctx context.Context, password string) (string, bool, error) {
+            if !strings.ContainsAny(password, "0123456789") {
+                return "", false, errDigits
+            }
+            return "", false, nil
+        }),
+        NewHandler("strength", func(ctx context.Context, password string) (string, bool, error) {
+            return "weak", true, nil
+        }),
+    )
+
+    responses, trace, err := chain.Broadcast(context.Background(), "abc")
+    if !errors.Is(err, errShort) || !errors.Is(err, errDigits) {
+        t.Errorf("Expected both validation errors, got %v", err)
+    }
+    if !reflect.DeepEqual(responses, []string{"weak"}) {
+        t.Errorf("Expected every handler to run, got %v", responses)
+    }
+    if trace.String() != "length:failed -> digits:failed -> strength:handled" {
+        t.Errorf("Unexpected trace %s", trace)
+    }
+
+    if _, _, err := chain.Broadcast(context.Background(), "abcdefgh1"); err != nil {
+        t.Errorf("Expected a valid password to pass, got %v", err)
+    }
+}
+
+func TestChainBroadcastContext(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    chain := NewChain(
+        NewHandler("cancel", func(ctx context.Context, request string) (string, bool, error) {
+            cancel()
+            return "first", true, nil
+        }),
+        letterHandler("A"),
+    )
+    responses, trace, err := chain.Broadcast(ctx, "A")
+    if !errors.Is(err, context.Canceled) || len(responses) != 1 || len(trace) != 1 {
+        t.Errorf("Expected the broadcast to stop after cancel, got %v, %s, %v", responses, trace, err)
+    }
+}
+
+func TestChainRace(t *testing.T) {
+    cancelled := make(chan string, 3)
+    chain := NewChain(
+        tierHandler("disk", time.Second, true, nil, cancelled),
+        tierHandler("memory", time.Millisecond, true, nil, cancelled),
+        tierHandler("remote", time.Second, true, nil, cancelled),
+    )
+
+    response, trace, err := chain.Race(context.Background(), "key")
+    if err != nil || response != "memory:key" {
+        t.Fatalf("Expected the memory tier to win, got '%s', %v", response, err)
+    }
+    if trace.String() != "disk:cancelled -> memory:handled -> remote:cancelled" {
+        t.Errorf("Unexpected trace %s", trace)
+    }
+    losers := []string{<-cancelled, <-cancelled}
+    if !(losers[0] == "disk" && losers[1] == "remote" || losers[0] == "remote" && losers[1] == "disk") {
+        t.Errorf("Expected disk and remote to be cancelled, got %v", losers)
+    }
+}
+
+func TestChainRaceSkipsFailuresAndPasses(t *testing.T) {
+    errDown := errors.New("down")
+    chain := NewChain(
+        tierHandler("broken", time.Millisecond, false, errDown, nil),
+        tierHandler("miss", time.Millisecond, false, nil, nil),
+        tierHandler("slow", 20*time.Millisecond, true, nil, nil),
+    )
+    response, _, err := chain.Race(context.Background(), "key")
+    if err != nil || response != "slow:key" {
+        t.Errorf("Expected the only success to win, got '%s', %v", response, err)
+    }
+
+    chain = NewChain(
+        tierHandler("broken", time.Millisecond, false, errDown, nil),
+        tierHandler("miss", time.Millisecond, false, nil, nil),
+    )
+    _, trace, err := chain.Race(context.Background(), "key")
+    var handlerErr *HandlerError
+    if !errors.Is(err, ErrUnhandled) || !errors.As(err, &handlerErr) || handlerErr.Handler != "broken" {
+        t.Errorf("Expected ErrUnhandled wrapping the handler error, got %v", err)
+    }
+    if trace.String() != "broken:failed -> miss:passed" {
+        t.Errorf("Unexpected trace %s", trace)
+    }
+}
+
+func TestChainRaceContext(t *testing.T) {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    chain := NewChain(tierHandler("slow", time.Second, true, nil, nil))
+    if _, _, err := chain.Race(ctx, "key"); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+    }
+}
+
+func TestChainQuorum(t *testing.T) {
+    cancelled := make(chan string, 1)
+    chain := NewChain(
+        tierHandler("a", 5*time.Millisecond, true, nil, cancelled),
+        tierHandler("b", time.Second, true, nil, cancelled),
+        tierHandler("c", 10*time.Millisecond, true, nil, cancelled),
+    )
+    responses, trace, err := chain.Quorum(context.Background(), "write", 2)
+    if err != nil || !reflect.DeepEqual(responses, []string{"a:write", "c:write"}) {
+        t.Fatalf("Expected a and c in chain order, got %v, %v", responses, err)
+    }
+    if trace.String() != "a:handled -> b:cancelled -> c:handled" {
+        t.Errorf("Unexpected trace %s", trace)
+    }
+    if loser := <-cancelled; loser != "b" {
+        t.Errorf("Expected b to be cancelled, got %s", loser)
+    }
+}
+
+func TestChainQuorumNotMet(t *testing.T) {
+    errDown := errors.New("down")
+    chain := NewChain(
+        tierHandler("a", time.Millisecond, false, errDown, nil),
+        tierHandler("b", 2*time.Millisecond, false, errDown, nil),
+        tierHandler("c", time.Second, true, nil, nil),
+    )
+    start := time.Now()
+    _, _, err := chain.Quorum(context.Background(), "write", 2)
+    if !errors.Is(err, ErrQuorumNotMet) || !errors.Is(err, errDown) {
+        t.Fatalf("Expected ErrQuorumNotMet wrapping errDown, got %v", err)
+    }
+    if time.Since(start) > 500*time.Millisecond {
+        t.Error("Expected Quorum to fail as soon as the quorum became impossible")
+    }
+
+    if _, _, err := chain.Quorum(context.Background(), "write", 4); !errors.Is(err, ErrQuorumNotMet) {
+        t.Errorf("Expected a quorum larger than the chain to fail, got %v", err)
+    }
+}
+
+func TestChainManagerModes(t *testing.T) {
+    manager := NewChainManager[string, string]()
+    manager.Add(tierHandler("fast", time.Millisecond, true, nil, nil), 0)
+    manager.Add(tierHandler("slow", 10*time.Millisecond, true, nil, nil), 1)
+
+    if response, _, err := manager.Race(context.Background(), "x"); err != nil || response != "fast:x" {
+        t.Errorf("Unexpected race result '%s', %v", response, err)
+    }
+    if responses, _, err := manager.Quorum(context.Background(), "x", 2); err != nil || len(responses) != 2 {
+        t.Errorf("Unexpected quorum result %v, %v", responses, err)
+    }
+    if responses, _, err := manager.Broadcast(context.Background(), "x"); err != nil || len(responses) != 2 {
+        t.Errorf("Unexpected broadcast result %v, %v", responses, err)
+    }
+}
@@ -1,5 +1,10 @@
 package command
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Command interface {
     Execute() string
     Undo() string
@@ -22,9 +27,24 @@ func (r *Receiver) GetState() string {
     return r.state
 }
 
+type ReceiverSnapshot struct {
+    state string
+}
+
+func (r *Receiver) Snapshot() ReceiverSnapshot {
+    return ReceiverSnapshot{state: r.state}
+}
+
+func (r *Receiver) Restore(snapshot ReceiverSnapshot) string {
+    r.state = snapshot.state
+    return "Receiver: " + r.state
+}
+
+// ConcreteCommandA undoes itself by restoring a snapshot of the receiver taken before it ran
 type ConcreteCommandA struct {
     receiver *Receiver
     param    string
+    before   ReceiverSnapshot
 }
 
 func NewConcreteCommandA(receiver *Receiver, param string) *ConcreteCommandA {
@@ -32,16 +52,23 @@ func NewConcreteCommandA(receiver *Receiver, param string) *ConcreteCommandA {
 }
 
 func (c *ConcreteCommandA) Execute() string {
+    c.before = c.receiver.Snapshot()
     return c.receiver.Action(c.param)
 }
 
 func (c *ConcreteCommandA) Undo() string {
-    return c.receiver.Action("initial")
+    return c.receiver.Restore(c.before)
+}
+
+func (c *ConcreteCommandA) Name() string {
+    return "CommandA(" + c.param + ")"
 }
 
+// ConcreteCommandB undoes itself with the inverse operation: setting the state it replaced
 type ConcreteCommandB struct {
     receiver *Receiver
     param    string
+    previous string
 }
 
 func NewConcreteCommandB(receiver *Receiver, param string) *ConcreteCommandB {
@@ -49,24 +76,78 @@ func NewConcreteCommandB(receiver *Receiver, param string) *ConcreteCommandB {
 }
 
 func (c *ConcreteCommandB) Execute() string {
+    c.previous = c.receiver.GetState()
     return c.receiver.Action(c.param)
 }
 
 func (c *ConcreteCommandB) Undo() string {
-    return c.receiver.Action("initial")
+    return c.receiver.Action(c.previous)
+}
+
+func (c *ConcreteCommandB) Name() string {
+    return "CommandB(" + c.param + ")"
+}
+
+type Namer interface {
+    Name() string
+}
+
+func CommandName(cmd Command) string {
+    if n, ok := cmd.(Namer); ok {
+        return n.Name()
+    }
+    return fmt.Sprintf("%T", cmd)
+}
+
+type MacroCommand struct {
+    name     string
+    commands []Command
+}
+
+func NewMacroCommand(name string, commands ...Command) *MacroCommand {
+    return &MacroCommand{name: name, commands: commands}
+}
+
+func (m *MacroCommand) Execute() string {
+    results := make([]string, len(m.commands))
+    for i, cmd := range m.commands {
+        results[i] = cmd.Execute()
+    }
+    return strings.Join(results, "\n")
+}
+
+// Undo reverts the commands in reverse order so each one sees the state it left behind
+func (m *MacroCommand) Undo() string {
+    results := make([]string, len(m.commands))
+    for i := len(m.commands) - 1; i >= 0; i-- {
+        results[len(m.commands)-1-i] = m.commands[i].Undo()
+    }
+    return strings.Join(results, "\n")
+}
+
+func (m *MacroCommand) Name() string {
+    return m.name
 }
 
 type Invoker struct {
     history []Command
+    redo    []Command
+    limit   int
 }
 
 func NewInvoker() *Invoker {
     return &Invoker{history: make([]Command, 0)}
 }
 
+// NewInvokerWithLimit keeps at most limit commands to undo, forgetting the oldest first
+func NewInvokerWithLimit(limit int) *Invoker {
+    return &Invoker{history: make([]Command, 0), limit: limit}
+}
+
 func (i *Invoker) ExecuteCommand(cmd Command) string {
     result := cmd.Execute()
-    i.history = append(i.history, cmd)
+    i.push(cmd)
+    i.redo = nil
     return result
 }
 
@@ -76,9 +157,53 @@ func (i *Invoker) UndoLastCommand() string {
     }
     lastCmd := i.history[len(i.history)-1]
     i.history = i.history[:len(i.history)-1]
+    i.redo = append(i.redo, lastCmd)
     return lastCmd.Undo()
 }
 
+func (i *Invoker) RedoLastCommand() string {
+    if len(i.redo) == 0 {
+        return "No commands to redo"
+    }
+    cmd := i.redo[len(i.redo)-1]
+    i.redo = i.redo[:len(i.redo)-1]
+    i.push(cmd)
+    return cmd.Execute()
+}
+
+func (i *Invoker) CanUndo() bool {
+    return len(i.history) > 0
+}
+
+func (i *Invoker) CanRedo() bool {
+    return len(i.redo) > 0
+}
+
+// History lists the names of the commands that can be undone, next to undo first
+func (i *Invoker) History() []string {
+    return names(i.history)
+}
+
+// RedoHistory lists the names of the commands that can be redone, next to redo first
+func (i *Invoker) RedoHistory() []string {
+    return names(i.redo)
+}
+
+func (i *Invoker) push(cmd Command) {
+    i.history = append(i.history, cmd)
+    if i.limit > 0 && len(i.history) > i.limit {
+        i.history = append(i.history[:0], i.history[len(i.history)-i.limit:]...)
+    }
+}
+
+func names(stack []Command) []string {
+    result := make([]string, len(stack))
+    for i, cmd := range stack {
+        result[len(stack)-1-i] = CommandName(cmd)
+    }
+    return result
+}
+
 type Client struct {
     invoker  *Invoker
     receiver *Receiver
@@ -98,4 +223,8 @@ func (c *Client) RunCommand(param string) string {
 
 func (c *Client) UndoLastCommand() string {
     return c.invoker.UndoLastCommand()
+}
+
+func (c *Client) RedoLastCommand() string {
+    return c.invoker.RedoLastCommand()
 } 
\ No newline at end of file
@@ -1,6 +1,9 @@
 package command
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestReceiver(t *testing.T) {
     receiver := NewReceiver()
@@ -68,7 +71,7 @@ func TestInvoker(t *testing.T) {
     }
 
     // Undo last command
-    expected = "Receiver: initial"
+    expected = "Receiver: A"
     if result := invoker.UndoLastCommand(); result != expected {
         t.Errorf("Expected '%s', got '%s'", expected, result)
     }
@@ -100,4 +103,132 @@ func TestClient(t *testing.T) {
     if result := client.UndoLastCommand(); result != expected {
         t.Errorf("Expected '%s', got '%s'", expected, result)
     }
-} 
\ No newline at end of file
+}
+
+func TestInvokerRedo(t *testing.T) {
+    invoker := NewInvoker()
+    receiver := NewReceiver()
+
+    invoker.ExecuteCommand(NewConcreteCommandA(receiver, "A"))
+    if invoker.CanRedo() {
+        t.Error("Expected nothing to redo")
+    }
+    invoker.UndoLastCommand()
+    if invoker.CanUndo() || !invoker.CanRedo() {
+        t.Error("Expected only redo to be possible")
+    }
+
+    expected := "Receiver: A"
+    if result := invoker.RedoLastCommand(); result != expected {
+        t.Errorf("Expected '%s', got '%s'", expected, result)
+    }
+    if state := receiver.GetState(); state != "A" {
+        t.Errorf("Expected state 'A', got '%s'", state)
+    }
+
+    // Redone commands can be undone again
+    if !invoker.CanUndo() || invoker.CanRedo() {
+        t.Error("Expected only undo to be possible")
+    }
+    expected = "No commands to redo"
+    if result := invoker.RedoLastCommand(); result != expected {
+        t.Errorf("Expected '%s', got '%s'", expected, result)
+    }
+}
+
+func TestInvokerNewCommandClearsRedo(t *testing.T) {
+    invoker := NewInvoker()
+    receiver := NewReceiver()
+
+    invoker.ExecuteCommand(NewConcreteCommandA(receiver, "A"))
+    invoker.ExecuteCommand(NewConcreteCommandB(receiver, "B"))
+    invoker.UndoLastCommand()
+    invoker.UndoLastCommand()
+    if got := invoker.RedoHistory(); !reflect.DeepEqual(got, []string{"CommandA(A)", "CommandB(B)"}) {
+        t.Errorf("Unexpected redo history %v", got)
+    }
+
+    invoker.ExecuteCommand(NewConcreteCommandB(receiver, "C"))
+    if invoker.CanRedo() {
+        t.Error("Expected a new command to clear the redo stack")
+    }
+    if got := invoker.History(); !reflect.DeepEqual(got, []string{"CommandB(C)"}) {
+        t.Errorf("Unexpected history %v", got)
+    }
+}
+
+func TestInvokerHistoryLimit(t *testing.T) {
+    invoker := NewInvokerWithLimit(2)
+    receiver := NewReceiver()
+    for _, param := range []string{"1", "2", "3"} {
+        invoker.ExecuteCommand(NewConcreteCommandA(receiver, param))
+    }
+
+    if got := invoker.History(); !reflect.DeepEqual(got, []string{"CommandA(3)", "CommandA(2)"}) {
+        t.Errorf("Expected the oldest command to be forgotten, got %v", got)
+    }
+    invoker.UndoLastCommand()
+    invoker.UndoLastCommand()
+    expected := "No commands to undo"
+    if result := invoker.UndoLastCommand(); result != expected {
+        t.Errorf("Expected '%s', got '%s'", expected, result)
+    }
+}
+
+func TestMacroCommand(t *testing.T) {
+    invoker := NewInvoker()
+    receiver := NewReceiver()
+    trail := []string{}
+    record := &recordingCommand{trail: &trail}
+
+    macro := NewMacroCommand("setup", NewConcreteCommandA(receiver, "A"), record, NewConcreteCommandB(receiver, "B"))
+    expected := "Receiver: A\nrecorded\nReceiver: B"
+    if result := invoker.ExecuteCommand(macro); result != expected {
+        t.Errorf("Expected '%s', got '%s'", expected, result)
+    }
+    if state := receiver.GetState(); state != "B" {
+        t.Errorf("Expected state 'B', got '%s'", state)
+    }
+    if got := invoker.History(); !reflect.DeepEqual(got, []string{"setup"}) {
+        t.Errorf("Expected the macro to be one history entry, got %v", got)
+    }
+
+    // The whole macro is undone as one unit, in reverse order
+    invoker.UndoLastCommand()
+    if state := receiver.GetState(); state != "initial" {
+        t.Errorf("Expected state 'initial', got '%s'", state)
+    }
+    if !reflect.DeepEqual(trail, []string{"execute", "undo"}) || invoker.CanUndo() {
+        t.Errorf("Unexpected trail %v", trail)
+    }
+
+    invoker.RedoLastCommand()
+    if state := receiver.GetState(); state != "B" {
+        t.Errorf("Expected redo to replay the macro, got '%s'", state)
+    }
+}
+
+func TestClientRedo(t *testing.T) {
+    client := NewClient()
+    client.RunCommand("test")
+    client.UndoLastCommand()
+
+    expected := "Receiver: test"
+    if result := client.RedoLastCommand(); result != expected {
+        t.Errorf("Expected '%s', got '%s'", expected, result)
+    }
+}
+
+type recordingCommand struct {
+    trail *[]string
+}
+
+func (c *recordingCommand) Execute() string {
+    *c.trail = append(*c.trail, "execute")
+    return "recorded"
+}
+
+func (c *recordingCommand) Undo() string {
+    *c.trail = append(*c.trail, "undo")
+    return "unrecorded"
+}
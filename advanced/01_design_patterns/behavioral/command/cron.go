@@ -0,0 +1,145 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression with the five standard fields:
+// minute, hour, day of month, month and day of week
+type CronSchedule struct {
+    minute, hour, dom, month, dow uint64
+    // domAny and dowAny record a day field starting with "*"; when both are
+    // restricted, a day matching either one matches, as in standard cron
+    domAny, dowAny bool
+    spec           string
+}
+
+var cronAliases = map[string]string{
+    "@yearly":   "0 0 1 1 *",
+    "@annually": "0 0 1 1 *",
+    "@monthly":  "0 0 1 * *",
+    "@weekly":   "0 0 * * 0",
+    "@daily":    "0 0 * * *",
+    "@midnight": "0 0 * * *",
+    "@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a cron expression such as "*/15 9-17 * * 1-5". Each field
+// accepts "*", numbers, ranges "a-b", steps "*/n" or "a-b/n" and comma
+// separated lists of those. Day of week runs from 0 (Sunday) to 6, with 7
+// also meaning Sunday. The aliases @yearly, @monthly, @weekly, @daily and
+// @hourly are accepted.
+func ParseCron(spec string) (*CronSchedule, error) {
+    expr := spec
+    if alias, ok := cronAliases[strings.TrimSpace(spec)]; ok {
+        expr = alias
+    }
+    fields := strings.Fields(expr)
+    if len(fields) != 5 {
+        return nil, fmt.Errorf("command: cron %q: expected 5 fields, got %d", spec, len(fields))
+    }
+    c := &CronSchedule{spec: spec}
+    var err error
+    parse := func(field string, min, max int) uint64 {
+        if err != nil {
+            return 0
+        }
+        var bits uint64
+        bits, err = parseCronField(field, min, max)
+        if err != nil {
+            err = fmt.Errorf("command: cron %q: field %q: %w", spec, field, err)
+        }
+        return bits
+    }
+    c.minute = parse(fields[0], 0, 59)
+    c.hour = parse(fields[1], 0, 23)
+    c.dom = parse(fields[2], 1, 31)
+    c.month = parse(fields[3], 1, 12)
+    c.dow = parse(fields[4], 0, 7)
+    if err != nil {
+        return nil, err
+    }
+    if c.dow&(1<<7) != 0 {
+        c.dow |= 1
+    }
+    c.domAny = strings.HasPrefix(fields[2], "*")
+    c.dowAny = strings.HasPrefix(fields[4], "*")
+    return c, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+    var bits uint64
+    for _, part := range strings.Split(field, ",") {
+        rangePart, step := part, 1
+        if i := strings.Index(part, "/"); i >= 0 {
+            n, err := strconv.Atoi(part[i+1:])
+            if err != nil || n <= 0 {
+                return 0, fmt.Errorf("bad step %q", part[i+1:])
+            }
+            rangePart, step = part[:i], n
+        }
+        lo, hi := min, max
+        if rangePart != "*" {
+            bounds := strings.SplitN(rangePart, "-", 2)
+            var err error
+            if lo, err = strconv.Atoi(bounds[0]); err != nil {
+                return 0, fmt.Errorf("bad value %q", bounds[0])
+            }
+            hi = lo
+            if len(bounds) == 2 {
+                if hi, err = strconv.Atoi(bounds[1]); err != nil {
+                    return 0, fmt.Errorf("bad value %q", bounds[1])
+                }
+            } else if step > 1 {
+                // "a/n" means from a to the end of the range
+                hi = max
+            }
+        }
+        if lo < min || hi > max || lo > hi {
+            return 0, fmt.Errorf("range %d-%d outside %d-%d", lo, hi, min, max)
+        }
+        for v := lo; v <= hi; v += step {
+            bits |= 1 << v
+        }
+    }
+    return bits, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (c *CronSchedule) String() string {
+    return c.spec
+}
+
+// Next returns the first time after t that matches the schedule, in t's
+// location, or the zero time if there is none within five years
+func (c *CronSchedule) Next(t time.Time) time.Time {
+    t = t.Truncate(time.Minute).Add(time.Minute)
+    limit := t.AddDate(5, 0, 0)
+    for t.Before(limit) {
+        switch {
+        case c.month&(1<<uint(t.Month())) == 0:
+            t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+        case !c.dayMatches(t):
+            t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+        case c.hour&(1<<uint(t.Hour())) == 0:
+            t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+        case c.minute&(1<<uint(t.Minute())) == 0:
+            t = t.Add(time.Minute)
+        default:
+            return t
+        }
+    }
+    return time.Time{}
+}
+
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+    dom := c.dom&(1<<uint(t.Day())) != 0
+    dow := c.dow&(1<<uint(t.Weekday())) != 0
+    if c.domAny || c.dowAny {
+        return dom && dow
+    }
+    return dom || dow
+}
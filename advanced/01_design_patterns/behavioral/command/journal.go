@@ -0,0 +1,439 @@
+package command
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrCorruptJournal is matched by a JournalError for a record that fails its checksum or cannot be decoded
+var ErrCorruptJournal = errors.New("command: corrupt journal record")
+
+// ErrTruncatedJournal is matched by a JournalError for a record cut short, e.g. by a crash mid-write
+var ErrTruncatedJournal = errors.New("command: truncated journal record")
+
+// ErrUnknownCommandType is returned when a journal names a command type missing from the registry
+var ErrUnknownCommandType = errors.New("command: unknown command type")
+
+// maxRecordSize bounds a record's length prefix so a corrupt prefix is not trusted with a huge allocation
+const maxRecordSize = 1 << 20
+
+const (
+    journalFile  = "journal.log"
+    snapshotFile = "snapshot.json"
+)
+
+// JournalError reports where a journal stops being readable
+type JournalError struct {
+    // Index is the number of good records before the bad one
+    Index int
+    // Offset is the byte offset of the bad record; everything before it is intact
+    Offset int64
+    Err    error
+}
+
+func (e *JournalError) Error() string {
+    return fmt.Sprintf("command: journal record %d at offset %d: %v", e.Index, e.Offset, e.Err)
+}
+
+func (e *JournalError) Unwrap() error {
+    return e.Err
+}
+
+// Journaled is a command that can be written to a journal
+type Journaled interface {
+    Command
+    // JournalType names the command in the TypeRegistry
+    JournalType() string
+    // JournalData returns everything needed to recreate the command
+    JournalData() any
+}
+
+// JournalEntry is one decoded journal record
+type JournalEntry struct {
+    Type string          `json:"type"`
+    Data json.RawMessage `json:"data"`
+}
+
+// DecodeFunc recreates a command bound to receiver from its journal data
+type DecodeFunc func(receiver *Receiver, data json.RawMessage) (Command, error)
+
+// TypeRegistry maps journal type names to decoders
+type TypeRegistry struct {
+    decoders map[string]DecodeFunc
+}
+
+// NewTypeRegistry creates a new, empty TypeRegistry
+func NewTypeRegistry() *TypeRegistry {
+    return &TypeRegistry{decoders: make(map[string]DecodeFunc)}
+}
+
+// Register adds a decoder under the given type name, replacing any previous one
+func (r *TypeRegistry) Register(commandType string, decode DecodeFunc) {
+    r.decoders[commandType] = decode
+}
+
+// Decode recreates the command for entry
+func (r *TypeRegistry) Decode(receiver *Receiver, entry JournalEntry) (Command, error) {
+    decode, ok := r.decoders[entry.Type]
+    if !ok {
+        return nil, fmt.Errorf("%w: %q", ErrUnknownCommandType, entry.Type)
+    }
+    return decode(receiver, entry.Data)
+}
+
+// paramData is the journal data of ConcreteCommandA and ConcreteCommandB
+type paramData struct {
+    Param string `json:"param"`
+}
+
+func (c *ConcreteCommandA) JournalType() string { return "commandA" }
+func (c *ConcreteCommandA) JournalData() any    { return paramData{Param: c.param} }
+func (c *ConcreteCommandB) JournalType() string { return "commandB" }
+func (c *ConcreteCommandB) JournalData() any    { return paramData{Param: c.param} }
+
+// NewStandardRegistry returns a registry of ConcreteCommandA and ConcreteCommandB
+func NewStandardRegistry() *TypeRegistry {
+    registry := NewTypeRegistry()
+    registry.Register("commandA", func(receiver *Receiver, data json.RawMessage) (Command, error) {
+        var d paramData
+        if err := json.Unmarshal(data, &d); err != nil {
+            return nil, err
+        }
+        return NewConcreteCommandA(receiver, d.Param), nil
+    })
+    registry.Register("commandB", func(receiver *Receiver, data json.RawMessage) (Command, error) {
+        var d paramData
+        if err := json.Unmarshal(data, &d); err != nil {
+            return nil, err
+        }
+        return NewConcreteCommandB(receiver, d.Param), nil
+    })
+    return registry
+}
+
+// Journal is an append-only file of commands. Each record is a 4-byte
+// big-endian payload length, a 4-byte CRC-32 of the payload, then the payload:
+// a JSON JournalEntry.
+type Journal struct {
+    file appendFile
+}
+
+// appendFile is the part of *os.File a Journal uses
+type appendFile interface {
+    io.WriteCloser
+    io.Seeker
+    Sync() error
+    Truncate(size int64) error
+}
+
+// OpenJournal opens the journal at path for appending, creating it if needed
+func OpenJournal(path string) (*Journal, error) {
+    file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+    if err != nil {
+        return nil, err
+    }
+    return &Journal{file: file}, nil
+}
+
+// Append writes cmd as one record and syncs it to disk
+func (j *Journal) Append(cmd Journaled) error {
+    data, err := json.Marshal(cmd.JournalData())
+    if err != nil {
+        return err
+    }
+    payload, err := json.Marshal(JournalEntry{Type: cmd.JournalType(), Data: data})
+    if err != nil {
+        return err
+    }
+    record := make([]byte, 8+len(payload))
+    binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+    binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+    copy(record[8:], payload)
+    offset, err := j.file.Seek(0, io.SeekEnd)
+    if err != nil {
+        return err
+    }
+    // A single write keeps a record contiguous; a crash can only leave a truncated tail.
+    // A failed write is cut off here, so later records never land after a torn one.
+    if _, err := j.file.Write(record); err != nil {
+        return errors.Join(err, j.file.Truncate(offset))
+    }
+    if err := j.file.Sync(); err != nil {
+        return errors.Join(err, j.file.Truncate(offset))
+    }
+    return nil
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+    return j.file.Close()
+}
+
+// ReadJournal calls fn for each record in r in order. It stops at the first
+// bad record with a *JournalError matching ErrTruncatedJournal or
+// ErrCorruptJournal, after calling fn for every good record before it.
+func ReadJournal(r io.Reader, fn func(index int, entry JournalEntry) error) error {
+    reader := bufio.NewReader(r)
+    var offset int64
+    for index := 0; ; index++ {
+        bad := func(err error) error {
+            return &JournalError{Index: index, Offset: offset, Err: err}
+        }
+        var header [8]byte
+        n, err := io.ReadFull(reader, header[:])
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            return bad(fmt.Errorf("%w: %d of 8 header bytes", ErrTruncatedJournal, n))
+        }
+        length := binary.BigEndian.Uint32(header[0:4])
+        if length > maxRecordSize {
+            return bad(fmt.Errorf("%w: length %d", ErrCorruptJournal, length))
+        }
+        payload := make([]byte, length)
+        if n, err := io.ReadFull(reader, payload); err != nil {
+            return bad(fmt.Errorf("%w: %d of %d payload bytes", ErrTruncatedJournal, n, length))
+        }
+        if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+            return bad(fmt.Errorf("%w: checksum mismatch", ErrCorruptJournal))
+        }
+        var entry JournalEntry
+        if err := json.Unmarshal(payload, &entry); err != nil {
+            return bad(fmt.Errorf("%w: %w", ErrCorruptJournal, err))
+        }
+        if err := fn(index, entry); err != nil {
+            return err
+        }
+        offset += int64(len(header)) + int64(length)
+    }
+}
+
+// JournalConfig configures a JournaledInvoker
+type JournalConfig struct {
+    // Dir holds journal.log and snapshot.json
+    Dir string
+    // SnapshotEvery writes a snapshot of the receiver after every N commands. Zero disables snapshots.
+    SnapshotEvery int
+    // Registry decodes journaled commands. Nil means NewStandardRegistry.
+    Registry *TypeRegistry
+    // RepairTail truncates a corrupt or truncated journal tail on open instead
+    // of failing. The open still fails when an intact record follows the bad
+    // one or the snapshot covers the bad record, since truncating would lose
+    // good commands.
+    RepairTail bool
+}
+
+// RecoveryReport describes how a JournaledInvoker rebuilt its receiver
+type RecoveryReport struct {
+    // SnapshotIndex is the number of commands covered by the snapshot used, zero if none
+    SnapshotIndex int
+    // Replayed is the number of commands replayed after the snapshot
+    Replayed int
+    // Discarded is the bad tail that RepairTail cut off, nil if the journal was intact
+    Discarded *JournalError
+}
+
+// snapshot is the on-disk form of a receiver snapshot
+type snapshot struct {
+    Index int    `json:"index"`
+    State string `json:"state"`
+}
+
+// JournaledInvoker executes commands against a receiver, journaling each one
+// first so the receiver can be rebuilt by replaying the journal. Undo is not
+// journaled: in an event-sourced log a reversal is recorded as a new command.
+type JournaledInvoker struct {
+    config   JournalConfig
+    receiver *Receiver
+    journal  *Journal
+    count    int
+    report   RecoveryReport
+}
+
+// OpenJournaledInvoker rebuilds a receiver from the snapshot and journal in
+// config.Dir and opens the journal for new commands
+func OpenJournaledInvoker(config JournalConfig) (*JournaledInvoker, error) {
+    if config.Registry == nil {
+        config.Registry = NewStandardRegistry()
+    }
+    receiver := NewReceiver()
+    inv := &JournaledInvoker{config: config, receiver: receiver}
+
+    snap, err := readSnapshot(filepath.Join(config.Dir, snapshotFile))
+    if err != nil {
+        return nil, err
+    }
+    receiver.Restore(ReceiverSnapshot{state: snap.State})
+    inv.report.SnapshotIndex = snap.Index
+
+    path := filepath.Join(config.Dir, journalFile)
+    if err := inv.replay(path, snap.Index); err != nil {
+        var journalErr *JournalError
+        if !config.RepairTail || !errors.As(err, &journalErr) {
+            return nil, err
+        }
+        if journalErr.Index < snap.Index {
+            return nil, fmt.Errorf("command: not repairing journal, the snapshot covers %d commands: %w", snap.Index, err)
+        }
+        intact, err := recordAfter(path, journalErr.Offset)
+        if err != nil {
+            return nil, err
+        }
+        if intact {
+            return nil, fmt.Errorf("command: not repairing journal, intact records follow the bad one: %w", journalErr)
+        }
+        if err := os.Truncate(path, journalErr.Offset); err != nil {
+            return nil, err
+        }
+        inv.report.Discarded = journalErr
+    }
+    if inv.count < snap.Index {
+        return nil, fmt.Errorf("command: snapshot covers %d commands but the journal has %d", snap.Index, inv.count)
+    }
+
+    inv.journal, err = OpenJournal(path)
+    if err != nil {
+        return nil, err
+    }
+    return inv, nil
+}
+
+// recordAfter reports whether a well-formed record starts anywhere after the
+// bad record at offset, in which case the damage is not confined to the tail
+func recordAfter(path string, offset int64) (bool, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return false, err
+    }
+    for start := offset + 1; start+8 <= int64(len(data)); start++ {
+        length := int64(binary.BigEndian.Uint32(data[start : start+4]))
+        if length > maxRecordSize || start+8+length > int64(len(data)) {
+            continue
+        }
+        payload := data[start+8 : start+8+length]
+        if crc32.ChecksumIEEE(payload) == binary.BigEndian.Uint32(data[start+4:start+8]) && json.Valid(payload) {
+            return true, nil
+        }
+    }
+    return false, nil
+}
+
+// replay executes every journaled command after the first skip on the receiver
+func (inv *JournaledInvoker) replay(path string, skip int) error {
+    file, err := os.Open(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+    return ReadJournal(file, func(index int, entry JournalEntry) error {
+        inv.count++
+        if index < skip {
+            return nil
+        }
+        cmd, err := inv.config.Registry.Decode(inv.receiver, entry)
+        if err != nil {
+            return fmt.Errorf("command: journal record %d: %w", index, err)
+        }
+        cmd.Execute()
+        inv.report.Replayed++
+        return nil
+    })
+}
+
+// ExecuteCommand journals cmd, then executes it. A snapshot is written after
+// every SnapshotEvery commands.
+func (inv *JournaledInvoker) ExecuteCommand(cmd Journaled) (string, error) {
+    if err := inv.journal.Append(cmd); err != nil {
+        return "", err
+    }
+    result := cmd.Execute()
+    inv.count++
+    if inv.config.SnapshotEvery > 0 && inv.count%inv.config.SnapshotEvery == 0 {
+        if err := inv.writeSnapshot(); err != nil {
+            return result, err
+        }
+    }
+    return result, nil
+}
+
+// Receiver returns the rebuilt receiver
+func (inv *JournaledInvoker) Receiver() *Receiver {
+    return inv.receiver
+}
+
+// Recovery reports how the receiver was rebuilt when the invoker was opened
+func (inv *JournaledInvoker) Recovery() RecoveryReport {
+    return inv.report
+}
+
+// Close closes the journal
+func (inv *JournaledInvoker) Close() error {
+    return inv.journal.Close()
+}
+
+// writeSnapshot replaces the snapshot file atomically, so a crash leaves either
+// the old or the new one. The new file is synced before the rename and the
+// directory after it, so the rename can never expose an incomplete file.
+func (inv *JournaledInvoker) writeSnapshot() error {
+    data, err := json.Marshal(snapshot{Index: inv.count, State: inv.receiver.GetState()})
+    if err != nil {
+        return err
+    }
+    path := filepath.Join(inv.config.Dir, snapshotFile)
+    tmp := path + ".tmp"
+    f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+    if err != nil {
+        return err
+    }
+    if _, err := f.Write(data); err != nil {
+        f.Close()
+        return err
+    }
+    if err := f.Sync(); err != nil {
+        f.Close()
+        return err
+    }
+    if err := f.Close(); err != nil {
+        return err
+    }
+    if err := os.Rename(tmp, path); err != nil {
+        return err
+    }
+    return syncDir(inv.config.Dir)
+}
+
+// syncDir makes a rename in dir durable
+func syncDir(dir string) error {
+    d, err := os.Open(dir)
+    if err != nil {
+        return err
+    }
+    defer d.Close()
+    return d.Sync()
+}
+
+func readSnapshot(path string) (snapshot, error) {
+    snap := snapshot{State: NewReceiver().GetState()}
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return snap, nil
+    }
+    if err != nil {
+        return snap, err
+    }
+    if err := json.Unmarshal(data, &snap); err != nil {
+        return snap, fmt.Errorf("command: read snapshot: %w", err)
+    }
+    return snap, nil
+}
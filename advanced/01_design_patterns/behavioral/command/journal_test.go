@@ -0,0 +1,305 @@
+package command
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openJournaled(t *testing.T, config JournalConfig) *JournaledInvoker {
+    t.Helper()
+    inv, err := OpenJournaledInvoker(config)
+    if err != nil {
+        t.Fatalf("OpenJournaledInvoker failed: %v", err)
+    }
+    return inv
+}
+
+func runJournaled(t *testing.T, inv *JournaledInvoker, params ...string) {
+    t.Helper()
+    for i, param := range params {
+        var cmd Journaled = NewConcreteCommandA(inv.Receiver(), param)
+        if i%2 == 1 {
+            cmd = NewConcreteCommandB(inv.Receiver(), param)
+        }
+        if _, err := inv.ExecuteCommand(cmd); err != nil {
+            t.Fatalf("ExecuteCommand failed: %v", err)
+        }
+    }
+}
+
+func TestJournalRebuildsReceiver(t *testing.T) {
+    dir := t.TempDir()
+    inv := openJournaled(t, JournalConfig{Dir: dir})
+    runJournaled(t, inv, "one", "two", "three")
+    inv.Close()
+
+    rebuilt := openJournaled(t, JournalConfig{Dir: dir})
+    defer rebuilt.Close()
+    if state := rebuilt.Receiver().GetState(); state != "three" {
+        t.Errorf("Expected state 'three', got '%s'", state)
+    }
+    if report := rebuilt.Recovery(); report.Replayed != 3 || report.SnapshotIndex != 0 || report.Discarded != nil {
+        t.Errorf("Unexpected recovery %+v", report)
+    }
+
+    // New commands append after the replayed ones
+    runJournaled(t, rebuilt, "four")
+    rebuilt.Close()
+    if state := openJournaled(t, JournalConfig{Dir: dir}).Receiver().GetState(); state != "four" {
+        t.Errorf("Expected state 'four', got '%s'", state)
+    }
+}
+
+func TestJournalSnapshots(t *testing.T) {
+    dir := t.TempDir()
+    config := JournalConfig{Dir: dir, SnapshotEvery: 3}
+    inv := openJournaled(t, config)
+    runJournaled(t, inv, "1", "2", "3", "4", "5", "6", "7")
+    inv.Close()
+
+    rebuilt := openJournaled(t, config)
+    defer rebuilt.Close()
+    if report := rebuilt.Recovery(); report.SnapshotIndex != 6 || report.Replayed != 1 {
+        t.Errorf("Expected to replay 1 command after a snapshot of 6, got %+v", report)
+    }
+    if state := rebuilt.Receiver().GetState(); state != "7" {
+        t.Errorf("Expected state '7', got '%s'", state)
+    }
+}
+
+func TestJournalReplayIsDeterministic(t *testing.T) {
+    dir := t.TempDir()
+    inv := openJournaled(t, JournalConfig{Dir: dir})
+    runJournaled(t, inv, "a", "b", "c", "d")
+    inv.Close()
+
+    // Rebuilding with and without snapshots, repeatedly, gives the same state
+    var states []string
+    for i := 0; i < 3; i++ {
+        rebuilt := openJournaled(t, JournalConfig{Dir: dir})
+        states = append(states, rebuilt.Receiver().GetState())
+        rebuilt.Close()
+    }
+    var entries []JournalEntry
+    data, _ := os.ReadFile(filepath.Join(dir, journalFile))
+    ReadJournal(bytes.NewReader(data), func(index int, entry JournalEntry) error {
+        entries = append(entries, entry)
+        return nil
+    })
+    for _, state := range states {
+        if state != "d" {
+            t.Errorf("Expected every rebuild to reach 'd', got %v", states)
+        }
+    }
+    if len(entries) != 4 || entries[1].Type != "commandB" || string(entries[1].Data) != `{"param":"b"}` {
+        t.Errorf("Unexpected entries %+v", entries)
+    }
+}
+
+func TestJournalTruncatedTail(t *testing.T) {
+    dir := t.TempDir()
+    inv := openJournaled(t, JournalConfig{Dir: dir})
+    runJournaled(t, inv, "one", "two", "three")
+    inv.Close()
+
+    path := filepath.Join(dir, journalFile)
+    info, _ := os.Stat(path)
+    os.Truncate(path, info.Size()-5)
+
+    _, err := OpenJournaledInvoker(JournalConfig{Dir: dir})
+    var journalErr *JournalError
+    if !errors.Is(err, ErrTruncatedJournal) || !errors.As(err, &journalErr) || journalErr.Index != 2 {
+        t.Fatalf("Expected a truncated third record, got %v", err)
+    }
+
+    repaired := openJournaled(t, JournalConfig{Dir: dir, RepairTail: true})
+    if state := repaired.Receiver().GetState(); state != "two" {
+        t.Errorf("Expected state 'two', got '%s'", state)
+    }
+    if report := repaired.Recovery(); report.Discarded == nil || report.Discarded.Offset != journalErr.Offset {
+        t.Errorf("Expected the discarded tail to be reported, got %+v", report)
+    }
+    runJournaled(t, repaired, "again")
+    repaired.Close()
+
+    // After repair the journal is clean again
+    if state := openJournaled(t, JournalConfig{Dir: dir}).Receiver().GetState(); state != "again" {
+        t.Errorf("Expected state 'again', got '%s'", state)
+    }
+}
+
+func TestJournalCorruptRecord(t *testing.T) {
+    dir := t.TempDir()
+    inv := openJournaled(t, JournalConfig{Dir: dir})
+    runJournaled(t, inv, "one", "two", "three")
+    inv.Close()
+
+    path := filepath.Join(dir, journalFile)
+    data, _ := os.ReadFile(path)
+    // Flip a byte inside the second record's payload
+    first := 8 + int(data[3])
+    data[first+10] ^= 0xff
+    os.WriteFile(path, data, 0o644)
+
+    _, err := OpenJournaledInvoker(JournalConfig{Dir: dir})
+    var journalErr *JournalError
+    if !errors.Is(err, ErrCorruptJournal) || !errors.As(err, &journalErr) || journalErr.Index != 1 || journalErr.Offset != int64(first) {
+        t.Errorf("Expected a corrupt second record at offset %d, got %v", first, err)
+    }
+}
+
+// corruptRecord flips a byte inside the payload of the record at index
+func corruptRecord(t *testing.T, path string, index int) int64 {
+    t.Helper()
+    data, _ := os.ReadFile(path)
+    offset := 0
+    for range index {
+        offset += 8 + int(binary.BigEndian.Uint32(data[offset:]))
+    }
+    data[offset+10] ^= 0xff
+    os.WriteFile(path, data, 0o644)
+    return int64(len(data))
+}
+
+func TestJournalRepairKeepsIntactRecords(t *testing.T) {
+    dir := t.TempDir()
+    inv := openJournaled(t, JournalConfig{Dir: dir})
+    runJournaled(t, inv, "one", "two", "three")
+    inv.Close()
+
+    // A bad record in the middle is not a tail; cutting it would lose "three"
+    path := filepath.Join(dir, journalFile)
+    size := corruptRecord(t, path, 1)
+    if _, err := OpenJournaledInvoker(JournalConfig{Dir: dir, RepairTail: true}); !errors.Is(err, ErrCorruptJournal) {
+        t.Errorf("Expected ErrCorruptJournal, got %v", err)
+    }
+    if info, _ := os.Stat(path); info.Size() != size {
+        t.Errorf("Expected the journal left at %d bytes, got %d", size, info.Size())
+    }
+}
+
+func TestJournalRepairRespectsSnapshot(t *testing.T) {
+    dir := t.TempDir()
+    config := JournalConfig{Dir: dir, SnapshotEvery: 4}
+    inv := openJournaled(t, config)
+    runJournaled(t, inv, "1", "2", "3", "4", "5", "6")
+    inv.Close()
+
+    // Record 1 is covered by the snapshot, so the journal cannot be cut there
+    path := filepath.Join(dir, journalFile)
+    size := corruptRecord(t, path, 1)
+    config.RepairTail = true
+    if _, err := OpenJournaledInvoker(config); !errors.Is(err, ErrCorruptJournal) {
+        t.Errorf("Expected ErrCorruptJournal, got %v", err)
+    }
+    if info, _ := os.Stat(path); info.Size() != size {
+        t.Errorf("Expected the journal left at %d bytes, got %d", size, info.Size())
+    }
+
+    // The same holds for a torn last record that the snapshot already covers
+    dir = t.TempDir()
+    config = JournalConfig{Dir: dir, SnapshotEvery: 2}
+    inv = openJournaled(t, config)
+    runJournaled(t, inv, "1", "2")
+    inv.Close()
+    path = filepath.Join(dir, journalFile)
+    info, _ := os.Stat(path)
+    size = info.Size() - 5
+    os.Truncate(path, size)
+    config.RepairTail = true
+    if _, err := OpenJournaledInvoker(config); !errors.Is(err, ErrTruncatedJournal) {
+        t.Errorf("Expected ErrTruncatedJournal, got %v", err)
+    }
+    if info, _ := os.Stat(path); info.Size() != size {
+        t.Errorf("Expected the journal left at %d bytes, got %d", size, info.Size())
+    }
+}
+
+func TestJournalUnknownType(t *testing.T) {
+    dir := t.TempDir()
+    inv := openJournaled(t, JournalConfig{Dir: dir})
+    runJournaled(t, inv, "one")
+    inv.Close()
+
+    // An unknown type is a registry problem, so RepairTail must not discard it
+    _, err := OpenJournaledInvoker(JournalConfig{Dir: dir, Registry: NewTypeRegistry(), RepairTail: true})
+    if !errors.Is(err, ErrUnknownCommandType) {
+        t.Errorf("Expected ErrUnknownCommandType, got %v", err)
+    }
+}
+
+func TestReadJournalRejectsHugeLength(t *testing.T) {
+    record := []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}
+    err := ReadJournal(bytes.NewReader(record), func(int, JournalEntry) error { return nil })
+    if !errors.Is(err, ErrCorruptJournal) {
+        t.Errorf("Expected ErrCorruptJournal, got %v", err)
+    }
+}
+
+// tornFile writes half of the next record and fails, like a full disk
+type tornFile struct {
+    appendFile
+    tear bool
+}
+
+func (f *tornFile) Write(p []byte) (int, error) {
+    if !f.tear {
+        return f.appendFile.Write(p)
+    }
+    f.tear = false
+    n, _ := f.appendFile.Write(p[:len(p)/2])
+    return n, errors.New("no space left on device")
+}
+
+func TestJournalFailedAppendIsCutOff(t *testing.T) {
+    dir := t.TempDir()
+    inv := openJournaled(t, JournalConfig{Dir: dir})
+    runJournaled(t, inv, "one")
+
+    torn := &tornFile{appendFile: inv.journal.file, tear: true}
+    inv.journal.file = torn
+    if _, err := inv.ExecuteCommand(NewConcreteCommandA(inv.Receiver(), "lost")); err == nil {
+        t.Fatal("Expected the torn write to fail")
+    }
+    runJournaled(t, inv, "two")
+    inv.Close()
+
+    // Without the cut, "two" would sit behind a torn record and fail recovery
+    rebuilt := openJournaled(t, JournalConfig{Dir: dir})
+    defer rebuilt.Close()
+    if state := rebuilt.Receiver().GetState(); state != "two" {
+        t.Errorf("Expected state 'two', got '%s'", state)
+    }
+    if report := rebuilt.Recovery(); report.Replayed != 2 || report.Discarded != nil {
+        t.Errorf("Unexpected recovery %+v", report)
+    }
+}
+
+func TestJournalSnapshotIsComplete(t *testing.T) {
+    dir := t.TempDir()
+    inv := openJournaled(t, JournalConfig{Dir: dir, SnapshotEvery: 1})
+    runJournaled(t, inv, "one")
+    inv.Close()
+
+    if _, err := os.Stat(filepath.Join(dir, snapshotFile+".tmp")); !errors.Is(err, os.ErrNotExist) {
+        t.Errorf("Expected no leftover temp file, got %v", err)
+    }
+    snap, err := readSnapshot(filepath.Join(dir, snapshotFile))
+    if err != nil || snap.Index != 1 || snap.State != "one" {
+        t.Errorf("Unexpected snapshot %+v, %v", snap, err)
+    }
+}
+
+func TestJournalSnapshotAheadOfJournal(t *testing.T) {
+    dir := t.TempDir()
+    data, _ := json.Marshal(snapshot{Index: 5, State: "x"})
+    os.WriteFile(filepath.Join(dir, snapshotFile), data, 0o644)
+    if _, err := OpenJournaledInvoker(JournalConfig{Dir: dir}); err == nil {
+        t.Error("Expected a snapshot covering missing journal records to be reported")
+    }
+}
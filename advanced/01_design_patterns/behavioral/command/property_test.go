@@ -0,0 +1,128 @@
+package command
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// step is one generated command: kind 0 is ConcreteCommandA, 1 is
+// ConcreteCommandB and 2 is a macro of the following size steps
+type step struct {
+    kind  int
+    param string
+    size  int
+}
+
+// script is a random command sequence for testing/quick
+type script []step
+
+func (script) Generate(r *rand.Rand, size int) reflect.Value {
+    s := make(script, r.Intn(size+1))
+    for i := range s {
+        s[i] = step{kind: r.Intn(3), param: fmt.Sprintf("s%d", r.Intn(5)), size: 1 + r.Intn(3)}
+    }
+    return reflect.ValueOf(s)
+}
+
+// build turns the script into commands on receiver
+func (s script) build(receiver *Receiver) []Command {
+    var commands []Command
+    for i := 0; i < len(s); i++ {
+        switch s[i].kind {
+        case 0:
+            commands = append(commands, NewConcreteCommandA(receiver, s[i].param))
+        case 1:
+            commands = append(commands, NewConcreteCommandB(receiver, s[i].param))
+        default:
+            end := min(i+1+s[i].size, len(s))
+            inner := script(s[i+1 : end]).build(receiver)
+            commands = append(commands, NewMacroCommand("macro", inner...))
+            i = end - 1
+        }
+    }
+    return commands
+}
+
+var quickConfig = &quick.Config{MaxCount: 500}
+
+func TestPropertyExecuteThenUndoIsIdentity(t *testing.T) {
+    property := func(s script) bool {
+        receiver := NewReceiver()
+        for _, cmd := range s.build(receiver) {
+            before := receiver.GetState()
+            cmd.Execute()
+            cmd.Undo()
+            if receiver.GetState() != before {
+                return false
+            }
+            // Leave the command applied so the next one starts from a new state
+            cmd.Execute()
+        }
+        return true
+    }
+    if err := quick.Check(property, quickConfig); err != nil {
+        t.Error(err)
+    }
+}
+
+func TestPropertyUndoAllRestoresEveryState(t *testing.T) {
+    property := func(s script) bool {
+        receiver := NewReceiver()
+        invoker := NewInvoker()
+        states := []string{receiver.GetState()}
+        for _, cmd := range s.build(receiver) {
+            invoker.ExecuteCommand(cmd)
+            states = append(states, receiver.GetState())
+        }
+        // Undoing walks back through every intermediate state
+        for i := len(states) - 2; i >= 0; i-- {
+            invoker.UndoLastCommand()
+            if receiver.GetState() != states[i] {
+                return false
+            }
+        }
+        return !invoker.CanUndo()
+    }
+    if err := quick.Check(property, quickConfig); err != nil {
+        t.Error(err)
+    }
+}
+
+func TestPropertyUndoRedoMatchesModel(t *testing.T) {
+    // ops drives a random mix of execute, undo and redo; the model is the list of
+    // states the receiver has been through and a cursor into it
+    property := func(s script, ops []uint8) bool {
+        receiver := NewReceiver()
+        invoker := NewInvoker()
+        commands := s.build(receiver)
+        states := []string{receiver.GetState()}
+        cursor := 0
+        for _, op := range ops {
+            switch {
+            case op%3 == 0 && len(commands) > 0:
+                invoker.ExecuteCommand(commands[0])
+                commands = commands[1:]
+                states = append(states[:cursor+1], receiver.GetState())
+                cursor++
+            case op%3 == 1:
+                invoker.UndoLastCommand()
+                cursor = max(cursor-1, 0)
+            default:
+                invoker.RedoLastCommand()
+                cursor = min(cursor+1, len(states)-1)
+            }
+            if receiver.GetState() != states[cursor] ||
+                invoker.CanUndo() != (cursor > 0) ||
+                invoker.CanRedo() != (cursor < len(states)-1) {
+                return false
+            }
+        }
+        return true
+    }
+    if err := quick.Check(property, quickConfig); err != nil {
+        t.Error(err)
+    }
+}
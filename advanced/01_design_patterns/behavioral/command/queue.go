@@ -0,0 +1,356 @@
+package command
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned by Submit when the queue is at capacity
+var ErrQueueFull = errors.New("command: queue full")
+
+// ErrQueueClosed is returned by Submit once Drain or Close has been called,
+// and is the error of commands dead-lettered by Close
+var ErrQueueClosed = errors.New("command: queue closed")
+
+// AsyncCommand is a command run by a CommandQueue. Run should return once ctx is done.
+type AsyncCommand interface {
+    Run(ctx context.Context) error
+}
+
+// AsyncFunc adapts a function to the AsyncCommand interface
+type AsyncFunc func(ctx context.Context) error
+
+// Run implements the AsyncCommand interface
+func (f AsyncFunc) Run(ctx context.Context) error {
+    return f(ctx)
+}
+
+// FromCommand adapts a Command, which cannot fail, to the AsyncCommand interface
+func FromCommand(cmd Command) AsyncCommand {
+    return AsyncFunc(func(ctx context.Context) error {
+        cmd.Execute()
+        return nil
+    })
+}
+
+// QueueConfig configures a CommandQueue
+type QueueConfig struct {
+    // Capacity bounds the number of commands waiting to run, counting
+    // pending retries and running commands that may still be retried, so
+    // retries never take the queue over it. Zero means unbounded.
+    Capacity int
+    // Workers is the number of commands run at once. Defaults to 1.
+    Workers int
+    // MaxAttempts is how many times a command runs before it is dead-lettered. Defaults to 1.
+    MaxAttempts int
+    // BaseDelay is the wait before the first retry. It doubles with each further retry.
+    BaseDelay time.Duration
+    // MaxDelay caps the retry wait. Zero means no cap.
+    MaxDelay time.Duration
+    // Timeout bounds each attempt unless the command was submitted WithTimeout. Zero means no timeout.
+    Timeout time.Duration
+}
+
+// SubmitOption configures one submitted command
+type SubmitOption func(*queuedCommand)
+
+// WithPriority sets the command's priority. Higher priorities run first; the default is 0.
+func WithPriority(priority int) SubmitOption {
+    return func(q *queuedCommand) {
+        q.priority = priority
+    }
+}
+
+// WithTimeout bounds each attempt of the command, overriding QueueConfig.Timeout
+func WithTimeout(timeout time.Duration) SubmitOption {
+    return func(q *queuedCommand) {
+        q.timeout = timeout
+    }
+}
+
+// WithName labels the command in dead letters
+func WithName(name string) SubmitOption {
+    return func(q *queuedCommand) {
+        q.name = name
+    }
+}
+
+// DeadLetter is a command that failed every attempt
+type DeadLetter struct {
+    ID       uint64
+    Name     string
+    Attempts int
+    // Err is the last attempt's error, or the drain context's error for a command still queued when a drain was cut short
+    Err     error
+    Command AsyncCommand
+}
+
+// QueueMetrics reports the state and outcomes of a CommandQueue
+type QueueMetrics struct {
+    // Depth is the number of commands waiting to run, including ones waiting to retry
+    Depth     int
+    InFlight  int
+    Submitted int
+    Rejected  int
+    Succeeded int
+    // Retried counts failed attempts that were scheduled to run again
+    Retried      int
+    DeadLettered int
+}
+
+type queuedCommand struct {
+    id       uint64
+    name     string
+    cmd      AsyncCommand
+    priority int
+    timeout  time.Duration
+    attempts int
+    seq      uint64
+}
+
+// commandHeap orders commands by priority, then submission order
+type commandHeap []*queuedCommand
+
+func (h commandHeap) Len() int { return len(h) }
+func (h commandHeap) Less(i, j int) bool {
+    if h[i].priority != h[j].priority {
+        return h[i].priority > h[j].priority
+    }
+    return h[i].seq < h[j].seq
+}
+func (h commandHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *commandHeap) Push(x any)   { *h = append(*h, x.(*queuedCommand)) }
+func (h *commandHeap) Pop() any {
+    old := *h
+    item := old[len(old)-1]
+    *h = old[:len(old)-1]
+    return item
+}
+
+// CommandQueue runs commands in the background on a pool of workers, highest
+// priority first. Failed attempts are retried with exponential backoff and
+// commands that fail MaxAttempts times are moved to a dead-letter list.
+type CommandQueue struct {
+    config  QueueConfig
+    ctx     context.Context
+    cancel  context.CancelFunc
+    mu      sync.Mutex
+    ready   *sync.Cond
+    queue   commandHeap
+    retries map[*queuedCommand]*time.Timer
+    // reserved counts running commands with attempts left, whose retry already holds a place
+    reserved int
+    closed   bool
+    nextID   uint64
+    dead     []DeadLetter
+    metrics  QueueMetrics
+    workers  sync.WaitGroup
+}
+
+// NewCommandQueue creates a CommandQueue and starts its workers
+func NewCommandQueue(config QueueConfig) *CommandQueue {
+    config.Workers = max(config.Workers, 1)
+    config.MaxAttempts = max(config.MaxAttempts, 1)
+    ctx, cancel := context.WithCancel(context.Background())
+    q := &CommandQueue{config: config, ctx: ctx, cancel: cancel, retries: make(map[*queuedCommand]*time.Timer)}
+    q.ready = sync.NewCond(&q.mu)
+    for i := 0; i < config.Workers; i++ {
+        q.workers.Add(1)
+        go q.work()
+    }
+    return q
+}
+
+// Submit queues cmd and returns its ID. It fails with ErrQueueFull when the
+// queue is at capacity and ErrQueueClosed once Drain or Close has been called.
+func (q *CommandQueue) Submit(cmd AsyncCommand, opts ...SubmitOption) (uint64, error) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    if q.closed {
+        return 0, ErrQueueClosed
+    }
+    if q.config.Capacity > 0 && len(q.queue)+len(q.retries)+q.reserved >= q.config.Capacity {
+        q.metrics.Rejected++
+        return 0, ErrQueueFull
+    }
+    q.nextID++
+    item := &queuedCommand{id: q.nextID, seq: q.nextID, cmd: cmd, timeout: q.config.Timeout}
+    for _, opt := range opts {
+        opt(item)
+    }
+    if item.name == "" {
+        item.name = fmt.Sprintf("command-%d", item.id)
+    }
+    heap.Push(&q.queue, item)
+    q.metrics.Submitted++
+    q.ready.Signal()
+    return item.id, nil
+}
+
+// Drain stops accepting commands and waits until every queued command,
+// including pending retries, has succeeded or been dead-lettered. If ctx
+// ends first, running commands are cancelled, queued ones are dead-lettered
+// with ctx's error, and Drain returns ctx.Err() once the workers have stopped.
+func (q *CommandQueue) Drain(ctx context.Context) error {
+    q.mu.Lock()
+    q.closed = true
+    q.ready.Broadcast()
+    q.mu.Unlock()
+
+    done := make(chan struct{})
+    go func() {
+        q.workers.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+        q.cancel()
+        return nil
+    case <-ctx.Done():
+    }
+
+    q.mu.Lock()
+    q.abandon(ctx.Err())
+    q.mu.Unlock()
+    q.cancel()
+    <-done
+    return ctx.Err()
+}
+
+// Close stops the queue without waiting for queued work. Running commands
+// are cancelled, and queued commands and pending retries are dead-lettered
+// with ErrQueueClosed. Close returns once the workers have stopped.
+func (q *CommandQueue) Close() {
+    q.mu.Lock()
+    q.closed = true
+    q.abandon(ErrQueueClosed)
+    q.mu.Unlock()
+    q.cancel()
+    q.workers.Wait()
+}
+
+// abandon dead-letters every queued command and pending retry with err. The caller holds q.mu.
+func (q *CommandQueue) abandon(err error) {
+    for item, timer := range q.retries {
+        timer.Stop()
+        q.deadLetter(item, err)
+    }
+    clear(q.retries)
+    for len(q.queue) > 0 {
+        q.deadLetter(heap.Pop(&q.queue).(*queuedCommand), err)
+    }
+    q.ready.Broadcast()
+}
+
+// DeadLetters returns the commands that failed every attempt
+func (q *CommandQueue) DeadLetters() []DeadLetter {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    return append([]DeadLetter(nil), q.dead...)
+}
+
+// Metrics returns the queue's current depth and outcome counts
+func (q *CommandQueue) Metrics() QueueMetrics {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    metrics := q.metrics
+    metrics.Depth = len(q.queue) + len(q.retries)
+    return metrics
+}
+
+func (q *CommandQueue) work() {
+    defer q.workers.Done()
+    for {
+        q.mu.Lock()
+        // Wait for work; once closed, keep going while retries may still arrive
+        for len(q.queue) == 0 && !(q.closed && len(q.retries) == 0) {
+            q.ready.Wait()
+        }
+        if len(q.queue) == 0 {
+            q.mu.Unlock()
+            return
+        }
+        item := heap.Pop(&q.queue).(*queuedCommand)
+        item.attempts++
+        reserved := item.attempts < q.config.MaxAttempts
+        if reserved {
+            q.reserved++
+        }
+        q.metrics.InFlight++
+        q.mu.Unlock()
+
+        err := q.run(item)
+
+        q.mu.Lock()
+        q.metrics.InFlight--
+        if reserved {
+            q.reserved--
+        }
+        switch {
+        case err == nil:
+            q.metrics.Succeeded++
+        case item.attempts >= q.config.MaxAttempts || q.ctx.Err() != nil:
+            q.deadLetter(item, err)
+        default:
+            q.metrics.Retried++
+            q.scheduleRetry(item)
+        }
+        if q.closed {
+            // Let idle workers see whether the last retry has finished
+            q.ready.Broadcast()
+        }
+        q.mu.Unlock()
+    }
+}
+
+// run makes one attempt at item, turning a panic into an error
+func (q *CommandQueue) run(item *queuedCommand) (err error) {
+    ctx := q.ctx
+    if item.timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, item.timeout)
+        defer cancel()
+    }
+    defer func() {
+        if r := recover(); r != nil {
+            err = fmt.Errorf("command: %s panicked: %v", item.name, r)
+        }
+    }()
+    return item.cmd.Run(ctx)
+}
+
+// scheduleRetry requeues item after its backoff. The caller holds q.mu.
+func (q *CommandQueue) scheduleRetry(item *queuedCommand) {
+    delay := q.config.BaseDelay
+    for i := 1; i < item.attempts && delay > 0 && delay <= math.MaxInt64/2; i++ {
+        if q.config.MaxDelay > 0 && delay >= q.config.MaxDelay {
+            break
+        }
+        delay *= 2
+    }
+    if q.config.MaxDelay > 0 && delay > q.config.MaxDelay {
+        delay = q.config.MaxDelay
+    }
+    q.retries[item] = time.AfterFunc(delay, func() {
+        q.mu.Lock()
+        defer q.mu.Unlock()
+        // A drain that gave up may have dead-lettered the command already
+        if _, pending := q.retries[item]; !pending {
+            return
+        }
+        delete(q.retries, item)
+        heap.Push(&q.queue, item)
+        q.ready.Signal()
+    })
+}
+
+// deadLetter records item as failed. The caller holds q.mu.
+func (q *CommandQueue) deadLetter(item *queuedCommand, err error) {
+    q.dead = append(q.dead, DeadLetter{ID: item.id, Name: item.name, Attempts: item.attempts, Err: err, Command: item.cmd})
+    q.metrics.DeadLettered++
+}
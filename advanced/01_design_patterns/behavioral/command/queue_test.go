@@ -0,0 +1,239 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blocker returns a command that waits until release is closed
+func blocker(started chan<- struct{}, release <-chan struct{}) AsyncCommand {
+    return AsyncFunc(func(ctx context.Context) error {
+        close(started)
+        <-release
+        return nil
+    })
+}
+
+func TestCommandQueuePriority(t *testing.T) {
+    queue := NewCommandQueue(QueueConfig{Workers: 1})
+    started, release := make(chan struct{}), make(chan struct{})
+    queue.Submit(blocker(started, release))
+    <-started
+
+    var mu sync.Mutex
+    var order []string
+    record := func(name string) AsyncCommand {
+        return AsyncFunc(func(ctx context.Context) error {
+            mu.Lock()
+            defer mu.Unlock()
+            order = append(order, name)
+            return nil
+        })
+    }
+    queue.Submit(record("low"), WithPriority(-1))
+    queue.Submit(record("normal-1"))
+    queue.Submit(record("high"), WithPriority(10))
+    queue.Submit(record("normal-2"))
+    if depth := queue.Metrics().Depth; depth != 4 {
+        t.Errorf("Expected depth 4, got %d", depth)
+    }
+
+    close(release)
+    if err := queue.Drain(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    expected := []string{"high", "normal-1", "normal-2", "low"}
+    if !reflect.DeepEqual(order, expected) {
+        t.Errorf("Expected %v, got %v", expected, order)
+    }
+}
+
+func TestCommandQueueCapacity(t *testing.T) {
+    queue := NewCommandQueue(QueueConfig{Workers: 1, Capacity: 2})
+    started, release := make(chan struct{}), make(chan struct{})
+    queue.Submit(blocker(started, release))
+    <-started
+
+    noop := AsyncFunc(func(ctx context.Context) error { return nil })
+    queue.Submit(noop)
+    queue.Submit(noop)
+    if _, err := queue.Submit(noop); !errors.Is(err, ErrQueueFull) {
+        t.Errorf("Expected ErrQueueFull, got %v", err)
+    }
+    close(release)
+    queue.Drain(context.Background())
+
+    metrics := queue.Metrics()
+    if metrics.Submitted != 3 || metrics.Rejected != 1 || metrics.Succeeded != 3 || metrics.Depth != 0 {
+        t.Errorf("Unexpected metrics %+v", metrics)
+    }
+    if _, err := queue.Submit(noop); !errors.Is(err, ErrQueueClosed) {
+        t.Errorf("Expected ErrQueueClosed after Drain, got %v", err)
+    }
+}
+
+func TestCommandQueueCapacityCountsRetries(t *testing.T) {
+    queue := NewCommandQueue(QueueConfig{Workers: 1, Capacity: 2, MaxAttempts: 3, BaseDelay: time.Hour})
+    defer queue.Close()
+    failed := make(chan struct{})
+    queue.Submit(AsyncFunc(func(ctx context.Context) error {
+        defer close(failed)
+        return errors.New("down")
+    }))
+    <-failed
+
+    noop := AsyncFunc(func(ctx context.Context) error { return nil })
+    // The failing command holds one place while it waits to retry
+    if _, err := queue.Submit(noop); err != nil {
+        t.Fatalf("Expected a second command to fit, got %v", err)
+    }
+    if _, err := queue.Submit(noop); !errors.Is(err, ErrQueueFull) {
+        t.Errorf("Expected ErrQueueFull with a retry pending, got %v", err)
+    }
+    if depth := queue.Metrics().Depth; depth > 2 {
+        t.Errorf("Expected depth within capacity, got %d", depth)
+    }
+}
+
+func TestCommandQueueClose(t *testing.T) {
+    queue := NewCommandQueue(QueueConfig{Workers: 1, MaxAttempts: 3, BaseDelay: time.Hour})
+    queue.Submit(AsyncFunc(func(ctx context.Context) error { return errors.New("down") }), WithName("retrying"))
+    started := make(chan struct{})
+    queue.Submit(AsyncFunc(func(ctx context.Context) error {
+        close(started)
+        <-ctx.Done()
+        return ctx.Err()
+    }), WithName("running"))
+    <-started
+    queue.Submit(AsyncFunc(func(ctx context.Context) error { return nil }), WithName("queued"))
+
+    queue.Close()
+
+    errs := map[string]error{}
+    for _, letter := range queue.DeadLetters() {
+        errs[letter.Name] = letter.Err
+    }
+    if !errors.Is(errs["retrying"], ErrQueueClosed) || !errors.Is(errs["queued"], ErrQueueClosed) {
+        t.Errorf("Expected waiting commands dead-lettered with ErrQueueClosed, got %v", errs)
+    }
+    if !errors.Is(errs["running"], context.Canceled) {
+        t.Errorf("Expected the running command to be cancelled, got %v", errs["running"])
+    }
+    if _, err := queue.Submit(AsyncFunc(func(ctx context.Context) error { return nil })); !errors.Is(err, ErrQueueClosed) {
+        t.Errorf("Expected ErrQueueClosed after Close, got %v", err)
+    }
+    queue.Close()
+}
+
+func TestCommandQueueRetries(t *testing.T) {
+    queue := NewCommandQueue(QueueConfig{MaxAttempts: 3, BaseDelay: time.Millisecond})
+    var attempts atomic.Int64
+    queue.Submit(AsyncFunc(func(ctx context.Context) error {
+        if attempts.Add(1) < 3 {
+            return errors.New("flaky")
+        }
+        return nil
+    }))
+    queue.Drain(context.Background())
+
+    metrics := queue.Metrics()
+    if attempts.Load() != 3 || metrics.Succeeded != 1 || metrics.Retried != 2 || metrics.DeadLettered != 0 {
+        t.Errorf("Expected success on the third attempt, got %d attempts and %+v", attempts.Load(), metrics)
+    }
+}
+
+func TestCommandQueueDeadLetters(t *testing.T) {
+    queue := NewCommandQueue(QueueConfig{Workers: 2, MaxAttempts: 3, BaseDelay: time.Millisecond})
+    errDown := errors.New("down")
+    id, _ := queue.Submit(AsyncFunc(func(ctx context.Context) error { return errDown }), WithName("sync-users"))
+    queue.Submit(AsyncFunc(func(ctx context.Context) error { panic("boom") }), WithName("explode"))
+    queue.Drain(context.Background())
+
+    dead := queue.DeadLetters()
+    if len(dead) != 2 {
+        t.Fatalf("Expected 2 dead letters, got %+v", dead)
+    }
+    for _, letter := range dead {
+        if letter.Attempts != 3 {
+            t.Errorf("Expected 3 attempts for %s, got %d", letter.Name, letter.Attempts)
+        }
+        if letter.Name == "sync-users" && (letter.ID != id || !errors.Is(letter.Err, errDown)) {
+            t.Errorf("Unexpected dead letter %+v", letter)
+        }
+        if letter.Name == "explode" && letter.Err == nil {
+            t.Error("Expected the panic to be reported as an error")
+        }
+    }
+    if metrics := queue.Metrics(); metrics.DeadLettered != 2 || metrics.Retried != 4 {
+        t.Errorf("Unexpected metrics %+v", metrics)
+    }
+}
+
+func TestCommandQueueTimeout(t *testing.T) {
+    queue := NewCommandQueue(QueueConfig{Timeout: time.Hour})
+    queue.Submit(AsyncFunc(func(ctx context.Context) error {
+        <-ctx.Done()
+        return ctx.Err()
+    }), WithTimeout(10*time.Millisecond))
+    queue.Drain(context.Background())
+
+    dead := queue.DeadLetters()
+    if len(dead) != 1 || !errors.Is(dead[0].Err, context.DeadlineExceeded) {
+        t.Errorf("Expected the command to time out, got %+v", dead)
+    }
+}
+
+func TestCommandQueueDrainDeadline(t *testing.T) {
+    queue := NewCommandQueue(QueueConfig{Workers: 1, MaxAttempts: 5, BaseDelay: time.Hour})
+    queue.Submit(AsyncFunc(func(ctx context.Context) error { return errors.New("down") }), WithName("retrying"))
+    started := make(chan struct{})
+    queue.Submit(AsyncFunc(func(ctx context.Context) error {
+        close(started)
+        <-ctx.Done()
+        return ctx.Err()
+    }), WithName("running"))
+    <-started
+    queue.Submit(AsyncFunc(func(ctx context.Context) error { return nil }), WithName("queued"))
+
+    ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+    defer cancel()
+    if err := queue.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+    }
+
+    names := map[string]bool{}
+    for _, letter := range queue.DeadLetters() {
+        names[letter.Name] = true
+    }
+    if !names["retrying"] || !names["running"] || !names["queued"] {
+        t.Errorf("Expected every unfinished command to be dead-lettered, got %v", names)
+    }
+    if metrics := queue.Metrics(); metrics.Depth != 0 || metrics.InFlight != 0 {
+        t.Errorf("Expected an empty queue, got %+v", metrics)
+    }
+}
+
+func TestCommandQueueConcurrentWorkers(t *testing.T) {
+    queue := NewCommandQueue(QueueConfig{Workers: 4, MaxAttempts: 2})
+    receiver := NewReceiver()
+    var mu sync.Mutex
+    var ran atomic.Int64
+    for i := 0; i < 100; i++ {
+        cmd := NewConcreteCommandA(receiver, "x")
+        queue.Submit(AsyncFunc(func(ctx context.Context) error {
+            mu.Lock()
+            defer mu.Unlock()
+            ran.Add(1)
+            return FromCommand(cmd).Run(ctx)
+        }))
+    }
+    queue.Drain(context.Background())
+    if ran.Load() != 100 || queue.Metrics().Succeeded != 100 {
+        t.Errorf("Expected 100 commands to run, got %d and %+v", ran.Load(), queue.Metrics())
+    }
+}
@@ -0,0 +1,333 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCronNeverMatches is returned by Scheduler.Cron for an expression with no
+// matching time, such as "0 0 30 2 *"
+var ErrCronNeverMatches = errors.New("command: cron expression never matches")
+
+// Clock abstracts time so tests can fast-forward a Scheduler
+type Clock interface {
+    Now() time.Time
+    After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the real-time Clock
+type SystemClock struct{}
+
+// Now implements the Clock interface
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// After implements the Clock interface
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// EntryID identifies a scheduled entry
+type EntryID uint64
+
+// MissedPolicy decides what happens to runs of a cron entry whose time passed
+// while the scheduler was not running, e.g. during downtime or a long command.
+// A one-off entry always runs once, however late it is.
+type MissedPolicy int
+
+const (
+    // MissedSkip drops missed runs and waits for the next scheduled time
+    MissedSkip MissedPolicy = iota
+    // MissedRunOnce runs once for any number of missed runs
+    MissedRunOnce
+    // MissedCatchUp runs once for every missed run
+    MissedCatchUp
+)
+
+// SchedulerConfig configures a Scheduler
+type SchedulerConfig struct {
+    // Clock defaults to SystemClock
+    Clock Clock
+    // Missed is the default policy for entries scheduled without WithMissedPolicy
+    Missed MissedPolicy
+    // Grace is how late a run may be and still count as on time. Defaults to one second.
+    Grace time.Duration
+    // OnRun is called after each run with the command's result
+    OnRun func(id EntryID, scheduled time.Time, result string)
+}
+
+// EntryOption configures one scheduled entry
+type EntryOption func(*scheduledEntry)
+
+// WithMissedPolicy overrides the scheduler's missed-run policy for one entry
+func WithMissedPolicy(policy MissedPolicy) EntryOption {
+    return func(e *scheduledEntry) {
+        e.missed = policy
+    }
+}
+
+// EntryInfo describes a scheduled entry
+type EntryInfo struct {
+    ID   EntryID
+    Next time.Time
+    // Cron is the expression of a recurring entry, empty for a one-off
+    Cron string
+}
+
+type scheduledEntry struct {
+    id     EntryID
+    cmd    Command
+    next   time.Time
+    cron   *CronSchedule
+    missed MissedPolicy
+    // cancelled stops runs that Tick has already collected
+    cancelled bool
+}
+
+// Scheduler runs commands at a set time, after a delay or on a cron schedule.
+// Run drives it in real time; Tick runs whatever is due, which lets tests
+// advance a fake clock and check the outcome without sleeping.
+type Scheduler struct {
+    config  SchedulerConfig
+    clock   Clock
+    mu      sync.Mutex
+    entries map[EntryID]*scheduledEntry
+    // collected holds entries with runs Tick is about to dispatch, so Cancel
+    // can still stop a one-off entry that has left entries
+    collected map[EntryID]*scheduledEntry
+    nextID    EntryID
+    // wake interrupts Run's wait when the entries change
+    wake chan struct{}
+}
+
+// NewScheduler creates a new Scheduler
+func NewScheduler(config SchedulerConfig) *Scheduler {
+    clock := config.Clock
+    if clock == nil {
+        clock = SystemClock{}
+    }
+    if config.Grace <= 0 {
+        config.Grace = time.Second
+    }
+    return &Scheduler{
+        config:    config,
+        clock:     clock,
+        entries:   make(map[EntryID]*scheduledEntry),
+        collected: make(map[EntryID]*scheduledEntry),
+        wake:      make(chan struct{}, 1),
+    }
+}
+
+// At schedules cmd to run once at t. If t has already passed, cmd runs on
+// the next Tick whatever the missed-run policy.
+func (s *Scheduler) At(t time.Time, cmd Command, opts ...EntryOption) EntryID {
+    return s.add(&scheduledEntry{cmd: cmd, next: t}, opts)
+}
+
+// After schedules cmd to run once after d
+func (s *Scheduler) After(d time.Duration, cmd Command, opts ...EntryOption) EntryID {
+    return s.At(s.clock.Now().Add(d), cmd, opts...)
+}
+
+// Cron schedules cmd to run whenever the cron expression matches. It fails
+// with ErrCronNeverMatches if the expression has no time in the next five years.
+func (s *Scheduler) Cron(spec string, cmd Command, opts ...EntryOption) (EntryID, error) {
+    schedule, err := ParseCron(spec)
+    if err != nil {
+        return 0, err
+    }
+    next := schedule.Next(s.clock.Now())
+    if next.IsZero() {
+        return 0, fmt.Errorf("%w: %q", ErrCronNeverMatches, spec)
+    }
+    return s.add(&scheduledEntry{cmd: cmd, cron: schedule, next: next}, opts), nil
+}
+
+// Cancel removes a scheduled entry. It reports false if there was no such
+// entry. A run that has not started yet is stopped even if it is already due.
+func (s *Scheduler) Cancel(id EntryID) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    e, ok := s.entries[id]
+    if !ok {
+        if e, ok = s.collected[id]; !ok || e.cancelled {
+            return false
+        }
+    }
+    e.cancelled = true
+    delete(s.entries, id)
+    s.signal()
+    return true
+}
+
+// Entries lists the scheduled entries, soonest first
+func (s *Scheduler) Entries() []EntryInfo {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    infos := make([]EntryInfo, 0, len(s.entries))
+    for _, e := range s.entries {
+        info := EntryInfo{ID: e.id, Next: e.next}
+        if e.cron != nil {
+            info.Cron = e.cron.String()
+        }
+        infos = append(infos, info)
+    }
+    sort.Slice(infos, func(i, j int) bool {
+        if !infos[i].Next.Equal(infos[j].Next) {
+            return infos[i].Next.Before(infos[j].Next)
+        }
+        return infos[i].ID < infos[j].ID
+    })
+    return infos
+}
+
+// Tick runs every command that is due at the clock's current time, applying
+// each entry's missed-run policy, and returns the number of runs
+func (s *Scheduler) Tick() int {
+    type run struct {
+        entry     *scheduledEntry
+        scheduled time.Time
+    }
+    now := s.clock.Now()
+    var runs []run
+
+    s.mu.Lock()
+    for _, e := range s.sortedEntries() {
+        var due []time.Time
+        for !e.next.IsZero() && !e.next.After(now) {
+            due = append(due, e.next)
+            if e.cron == nil {
+                e.next = time.Time{}
+                break
+            }
+            // A catch-up needs every missed time; the other policies only the latest
+            if e.missed == MissedCatchUp || e.next.After(now.Add(-s.config.Grace)) {
+                e.next = e.cron.Next(e.next)
+            } else {
+                e.next = e.cron.Next(now.Add(-s.config.Grace))
+            }
+        }
+        policy := e.missed
+        if e.cron == nil {
+            policy = MissedRunOnce
+        }
+        for _, scheduled := range s.selectRuns(policy, due, now) {
+            runs = append(runs, run{entry: e, scheduled: scheduled})
+            s.collected[e.id] = e
+        }
+        if e.next.IsZero() {
+            delete(s.entries, e.id)
+        }
+    }
+    s.mu.Unlock()
+
+    // Catch-up runs of different entries interleave in time order
+    sort.SliceStable(runs, func(i, j int) bool { return runs[i].scheduled.Before(runs[j].scheduled) })
+    ran := 0
+    for _, r := range runs {
+        // Cancel may have been called since the runs were collected
+        s.mu.Lock()
+        cancelled := r.entry.cancelled
+        s.mu.Unlock()
+        if cancelled {
+            continue
+        }
+        ran++
+        result := r.entry.cmd.Execute()
+        if s.config.OnRun != nil {
+            s.config.OnRun(r.entry.id, r.scheduled, result)
+        }
+    }
+
+    s.mu.Lock()
+    for _, r := range runs {
+        delete(s.collected, r.entry.id)
+    }
+    s.mu.Unlock()
+    return ran
+}
+
+// Run calls Tick whenever an entry is due until ctx ends
+func (s *Scheduler) Run(ctx context.Context) error {
+    for {
+        s.Tick()
+        var wait <-chan time.Time
+        if next, ok := s.nextDue(); ok {
+            wait = s.clock.After(max(next.Sub(s.clock.Now()), 0))
+        }
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-wait:
+        case <-s.wake:
+        }
+    }
+}
+
+// selectRuns applies policy to the due times of one entry, returning the
+// scheduled times to run
+func (s *Scheduler) selectRuns(policy MissedPolicy, due []time.Time, now time.Time) []time.Time {
+    if len(due) == 0 {
+        return nil
+    }
+    switch policy {
+    case MissedCatchUp:
+        return due
+    case MissedRunOnce:
+        return due[len(due)-1:]
+    default:
+        latest := due[len(due)-1]
+        if latest.Before(now.Add(-s.config.Grace)) {
+            return nil
+        }
+        return []time.Time{latest}
+    }
+}
+
+func (s *Scheduler) add(e *scheduledEntry, opts []EntryOption) EntryID {
+    e.missed = s.config.Missed
+    for _, opt := range opts {
+        opt(e)
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.nextID++
+    e.id = s.nextID
+    if !e.next.IsZero() {
+        s.entries[e.id] = e
+    }
+    s.signal()
+    return e.id
+}
+
+// sortedEntries returns the entries in ID order, so runs due together happen
+// in the order they were scheduled. The caller holds s.mu.
+func (s *Scheduler) sortedEntries() []*scheduledEntry {
+    entries := make([]*scheduledEntry, 0, len(s.entries))
+    for _, e := range s.entries {
+        entries = append(entries, e)
+    }
+    sort.Slice(entries, func(i, j int) bool { return entries[i].id < entries[j].id })
+    return entries
+}
+
+func (s *Scheduler) nextDue() (time.Time, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var next time.Time
+    for _, e := range s.entries {
+        if next.IsZero() || e.next.Before(next) {
+            next = e.next
+        }
+    }
+    return next, !next.IsZero()
+}
+
+// signal wakes Run without blocking. The caller holds s.mu.
+func (s *Scheduler) signal() {
+    select {
+    case s.wake <- struct{}{}:
+    default:
+    }
+}
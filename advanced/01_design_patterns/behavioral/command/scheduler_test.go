@@ -0,0 +1,312 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when the test advances it
+type fakeClock struct {
+    mu     sync.Mutex
+    now    time.Time
+    timers []fakeTimer
+}
+
+type fakeTimer struct {
+    at time.Time
+    ch chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+    return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    ch := make(chan time.Time, 1)
+    if d <= 0 {
+        ch <- c.now
+        return ch
+    }
+    c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), ch: ch})
+    return ch
+}
+
+// Advance moves the clock forward and fires the timers that are due
+func (c *fakeClock) Advance(d time.Duration) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.now = c.now.Add(d)
+    pending := c.timers[:0]
+    for _, timer := range c.timers {
+        if timer.at.After(c.now) {
+            pending = append(pending, timer)
+        } else {
+            timer.ch <- c.now
+        }
+    }
+    c.timers = pending
+}
+
+func (c *fakeClock) pendingTimers() int {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return len(c.timers)
+}
+
+// runLog collects the commands a scheduler runs
+type runLog struct {
+    mu   sync.Mutex
+    runs []string
+}
+
+func (l *runLog) record(id EntryID, scheduled time.Time, result string) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.runs = append(l.runs, result+"@"+scheduled.Format("15:04"))
+}
+
+func (l *runLog) take() []string {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    runs := l.runs
+    l.runs = nil
+    return runs
+}
+
+var schedulerStart = time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC) // a Monday
+
+func newTestScheduler(policy MissedPolicy) (*Scheduler, *fakeClock, *runLog, *Receiver) {
+    clock := newFakeClock(schedulerStart)
+    log := &runLog{}
+    scheduler := NewScheduler(SchedulerConfig{Clock: clock, Missed: policy, OnRun: log.record})
+    return scheduler, clock, log, NewReceiver()
+}
+
+func TestSchedulerAtAndAfter(t *testing.T) {
+    scheduler, clock, log, receiver := newTestScheduler(MissedSkip)
+    scheduler.After(10*time.Minute, NewConcreteCommandA(receiver, "after"))
+    scheduler.At(schedulerStart.Add(5*time.Minute), NewConcreteCommandA(receiver, "at"))
+
+    clock.Advance(4 * time.Minute)
+    if n := scheduler.Tick(); n != 0 {
+        t.Errorf("Expected nothing due yet, got %d runs", n)
+    }
+    clock.Advance(time.Minute)
+    scheduler.Tick()
+    clock.Advance(5 * time.Minute)
+    scheduler.Tick()
+
+    expected := []string{"Receiver: at@08:05", "Receiver: after@08:10"}
+    if runs := log.take(); !reflect.DeepEqual(runs, expected) {
+        t.Errorf("Expected %v, got %v", expected, runs)
+    }
+    if len(scheduler.Entries()) != 0 {
+        t.Error("Expected one-off entries to be removed after running")
+    }
+}
+
+func TestSchedulerCancel(t *testing.T) {
+    scheduler, clock, log, receiver := newTestScheduler(MissedSkip)
+    id := scheduler.After(time.Minute, NewConcreteCommandA(receiver, "cancelled"))
+    cronID, _ := scheduler.Cron("* * * * *", NewConcreteCommandA(receiver, "cron"))
+
+    if !scheduler.Cancel(id) || !scheduler.Cancel(cronID) || scheduler.Cancel(id) {
+        t.Fatal("Expected each entry to cancel exactly once")
+    }
+    clock.Advance(time.Hour)
+    scheduler.Tick()
+    if runs := log.take(); len(runs) != 0 {
+        t.Errorf("Expected cancelled entries not to run, got %v", runs)
+    }
+}
+
+func TestSchedulerCron(t *testing.T) {
+    scheduler, clock, log, receiver := newTestScheduler(MissedSkip)
+    scheduler.Cron("*/15 8-9 * * 1-5", NewConcreteCommandA(receiver, "report"))
+
+    if entries := scheduler.Entries(); len(entries) != 1 || !entries[0].Next.Equal(schedulerStart.Add(15*time.Minute)) {
+        t.Fatalf("Unexpected entries %+v", entries)
+    }
+    for i := 0; i < 12; i++ {
+        clock.Advance(15 * time.Minute)
+        scheduler.Tick()
+    }
+    expected := []string{
+        "Receiver: report@08:15", "Receiver: report@08:30", "Receiver: report@08:45",
+        "Receiver: report@09:00", "Receiver: report@09:15", "Receiver: report@09:30", "Receiver: report@09:45",
+    }
+    if runs := log.take(); !reflect.DeepEqual(runs, expected) {
+        t.Errorf("Expected %v, got %v", expected, runs)
+    }
+    if next := scheduler.Entries()[0].Next; !next.Equal(schedulerStart.AddDate(0, 0, 1)) {
+        t.Errorf("Expected the next run tomorrow at 08:00, got %s", next)
+    }
+}
+
+func TestSchedulerMissedRuns(t *testing.T) {
+    tests := []struct {
+        policy   MissedPolicy
+        expected []string
+    }{
+        // Three runs at 08:10, 08:20 and 08:30 were missed during downtime; 08:40 is on time
+        {MissedSkip, []string{"Receiver: tick@08:40"}},
+        {MissedRunOnce, []string{"Receiver: tick@08:40"}},
+        {MissedCatchUp, []string{"Receiver: tick@08:10", "Receiver: tick@08:20", "Receiver: tick@08:30", "Receiver: tick@08:40"}},
+    }
+    for _, tt := range tests {
+        scheduler, clock, log, receiver := newTestScheduler(tt.policy)
+        scheduler.Cron("*/10 * * * *", NewConcreteCommandA(receiver, "tick"))
+        clock.Advance(40 * time.Minute)
+        scheduler.Tick()
+        if runs := log.take(); !reflect.DeepEqual(runs, tt.expected) {
+            t.Errorf("Policy %d: expected %v, got %v", tt.policy, tt.expected, runs)
+        }
+    }
+}
+
+func TestSchedulerMissedRunsOffSchedule(t *testing.T) {
+    // Downtime ends between runs, so nothing is on time
+    tests := []struct {
+        policy MissedPolicy
+        runs   int
+    }{
+        {MissedSkip, 0},
+        {MissedRunOnce, 1},
+        {MissedCatchUp, 3},
+    }
+    for _, tt := range tests {
+        scheduler, clock, log, receiver := newTestScheduler(MissedSkip)
+        scheduler.Cron("*/10 * * * *", NewConcreteCommandA(receiver, "tick"), WithMissedPolicy(tt.policy))
+        scheduler.At(schedulerStart.Add(5*time.Minute), NewConcreteCommandA(receiver, "once"), WithMissedPolicy(tt.policy))
+        clock.Advance(35 * time.Minute)
+        scheduler.Tick()
+
+        // The one-off entry runs once whatever the policy
+        runs := log.take()
+        if len(runs) != tt.runs+1 {
+            t.Errorf("Policy %d: expected %d runs, got %v", tt.policy, tt.runs+1, runs)
+        }
+        if next := scheduler.Entries()[0].Next; !next.Equal(schedulerStart.Add(40 * time.Minute)) {
+            t.Errorf("Policy %d: expected the next run at 08:40, got %s", tt.policy, next)
+        }
+    }
+}
+
+func TestSchedulerCronNeverMatches(t *testing.T) {
+    scheduler, _, _, receiver := newTestScheduler(MissedSkip)
+    if _, err := scheduler.Cron("0 0 30 2 *", NewConcreteCommandA(receiver, "never")); !errors.Is(err, ErrCronNeverMatches) {
+        t.Errorf("Expected ErrCronNeverMatches, got %v", err)
+    }
+    if entries := scheduler.Entries(); len(entries) != 0 {
+        t.Errorf("Expected no entries, got %v", entries)
+    }
+}
+
+func TestSchedulerOverdueAt(t *testing.T) {
+    scheduler, _, log, receiver := newTestScheduler(MissedSkip)
+    scheduler.At(schedulerStart.Add(-time.Hour), NewConcreteCommandA(receiver, "late"))
+    if n := scheduler.Tick(); n != 1 {
+        t.Errorf("Expected an overdue one-off entry to run once, got %d runs", n)
+    }
+    if runs := log.take(); len(runs) != 1 {
+        t.Errorf("Expected one run, got %v", runs)
+    }
+}
+
+// commandFunc is a Command that runs a function and cannot be undone
+type commandFunc func() string
+
+func (f commandFunc) Execute() string { return f() }
+func (f commandFunc) Undo() string    { return "" }
+
+func TestSchedulerCancelDuringTick(t *testing.T) {
+    scheduler, clock, log, receiver := newTestScheduler(MissedSkip)
+    var second EntryID
+    // The first run cancels the second, which Tick has already collected
+    scheduler.At(schedulerStart.Add(time.Minute), commandFunc(func() string {
+        scheduler.Cancel(second)
+        return "first"
+    }))
+    second = scheduler.At(schedulerStart.Add(time.Minute), NewConcreteCommandA(receiver, "second"))
+    clock.Advance(time.Minute)
+
+    if n := scheduler.Tick(); n != 1 {
+        t.Errorf("Expected only the first entry to run, got %d runs", n)
+    }
+    if runs := log.take(); len(runs) != 1 || runs[0] != "first@08:01" {
+        t.Errorf("Expected the cancelled entry not to run, got %v", runs)
+    }
+}
+
+func TestSchedulerRun(t *testing.T) {
+    scheduler, clock, log, receiver := newTestScheduler(MissedSkip)
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error)
+    go func() { done <- scheduler.Run(ctx) }()
+
+    scheduler.After(time.Minute, NewConcreteCommandA(receiver, "first"))
+    // Wait for Run to pick up the entry and start waiting on the clock
+    for clock.pendingTimers() == 0 {
+        time.Sleep(time.Millisecond)
+    }
+    clock.Advance(time.Minute)
+    var runs []string
+    for len(runs) == 0 {
+        time.Sleep(time.Millisecond)
+        runs = log.take()
+    }
+    cancel()
+    if err := <-done; err != context.Canceled {
+        t.Errorf("Expected context.Canceled, got %v", err)
+    }
+    if !reflect.DeepEqual(runs, []string{"Receiver: first@08:01"}) {
+        t.Errorf("Unexpected runs %v", runs)
+    }
+}
+
+func TestParseCron(t *testing.T) {
+    from := time.Date(2024, 1, 31, 23, 59, 0, 0, time.UTC) // a Wednesday
+    tests := []struct {
+        spec string
+        next time.Time
+    }{
+        {"* * * * *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+        {"@hourly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+        {"30 12 * * *", time.Date(2024, 2, 1, 12, 30, 0, 0, time.UTC)},
+        {"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+        {"0 9 * * 0", time.Date(2024, 2, 4, 9, 0, 0, 0, time.UTC)},
+        {"0 9 * * 7", time.Date(2024, 2, 4, 9, 0, 0, 0, time.UTC)},
+        // Both day fields restricted: either the 15th or a Friday
+        {"0 0 15 * 5", time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)},
+        {"5,10/20 1 * * *", time.Date(2024, 2, 1, 1, 5, 0, 0, time.UTC)},
+        {"0 0 31 4 *", time.Time{}},
+    }
+    for _, tt := range tests {
+        schedule, err := ParseCron(tt.spec)
+        if err != nil {
+            t.Errorf("%s: %v", tt.spec, err)
+            continue
+        }
+        if next := schedule.Next(from); !next.Equal(tt.next) {
+            t.Errorf("%s: expected %s, got %s", tt.spec, tt.next, next)
+        }
+    }
+
+    for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+        if _, err := ParseCron(spec); err == nil {
+            t.Errorf("Expected %q to be rejected", spec)
+        }
+    }
+}
@@ -1,5 +1,16 @@
 package iterator
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ErrConcurrentModification is reported by a fail-fast iterator whose
+// aggregate changed after the iterator was created
+var ErrConcurrentModification = errors.New("iterator: aggregate modified during iteration")
+
 // Iterator defines the interface for traversing elements
 type Iterator interface {
     Next() interface{}
@@ -40,9 +51,12 @@ func (i *ConcreteIterator) HasNext() bool {
     return i.position < len(i.collection)
 }
 
-// ConcreteAggregate implements the Aggregate interface
+// ConcreteAggregate implements the Aggregate interface. It is safe for
+// concurrent use.
 type ConcreteAggregate struct {
-    items []interface{}
+    mu      sync.RWMutex
+    items   []interface{}
+    version uint64
 }
 
 // NewConcreteAggregate creates a new ConcreteAggregate
@@ -54,12 +68,88 @@ func NewConcreteAggregate() *ConcreteAggregate {
 
 // AddItem adds an item to the aggregate
 func (a *ConcreteAggregate) AddItem(item interface{}) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
     a.items = append(a.items, item)
+    a.version++
+}
+
+// Len returns the number of items in the aggregate
+func (a *ConcreteAggregate) Len() int {
+    a.mu.RLock()
+    defer a.mu.RUnlock()
+    return len(a.items)
 }
 
-// CreateIterator creates a new iterator for the aggregate
+// CreateIterator creates a new iterator for the aggregate. It is a snapshot
+// iterator, see CreateSnapshotIterator.
 func (a *ConcreteAggregate) CreateIterator() Iterator {
-    return NewConcreteIterator(a.items)
+    return a.CreateSnapshotIterator()
+}
+
+// CreateSnapshotIterator creates an iterator over a copy of the items, so it
+// sees the aggregate as it was when created whatever happens afterwards
+func (a *ConcreteAggregate) CreateSnapshotIterator() *ConcreteIterator {
+    a.mu.RLock()
+    defer a.mu.RUnlock()
+    return NewConcreteIterator(slices.Clone(a.items))
+}
+
+// CreateFailFastIterator creates an iterator over the live items that stops
+// with ErrConcurrentModification once the aggregate is modified
+func (a *ConcreteAggregate) CreateFailFastIterator() *FailFastIterator {
+    a.mu.RLock()
+    defer a.mu.RUnlock()
+    return &FailFastIterator{aggregate: a, version: a.version}
+}
+
+// FailFastIterator iterates over a ConcreteAggregate without copying it. It
+// compares the aggregate's version counter with the one it started from on
+// every step. After a modification HasNext returns false and Err reports
+// ErrConcurrentModification.
+type FailFastIterator struct {
+    aggregate *ConcreteAggregate
+    version   uint64
+    position  int
+    err       error
+}
+
+// Next returns the next element in the collection, or nil at the end or
+// after a modification
+func (i *FailFastIterator) Next() interface{} {
+    i.aggregate.mu.RLock()
+    defer i.aggregate.mu.RUnlock()
+    if !i.check() {
+        return nil
+    }
+    item := i.aggregate.items[i.position]
+    i.position++
+    return item
+}
+
+// HasNext checks if there are more elements to iterate
+func (i *FailFastIterator) HasNext() bool {
+    i.aggregate.mu.RLock()
+    defer i.aggregate.mu.RUnlock()
+    return i.check()
+}
+
+// Err returns ErrConcurrentModification if iteration stopped because the
+// aggregate was modified, and nil otherwise
+func (i *FailFastIterator) Err() error {
+    return i.err
+}
+
+// check reports whether another element can be read. The caller holds the read lock.
+func (i *FailFastIterator) check() bool {
+    if i.err != nil {
+        return false
+    }
+    if current := i.aggregate.version; current != i.version {
+        i.err = fmt.Errorf("%w: version %d, iterator created at %d", ErrConcurrentModification, current, i.version)
+        return false
+    }
+    return i.position < len(i.aggregate.items)
 }
 
 // Client represents a client that uses the iterator pattern
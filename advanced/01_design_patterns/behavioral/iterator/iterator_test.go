@@ -1,6 +1,10 @@
 package iterator
 
-import "testing"
+import (
+	"errors"
+	"sync"
+	"testing"
+)
 
 func TestConcreteIterator(t *testing.T) {
     collection := []interface{}{1, 2, 3}
@@ -68,4 +72,107 @@ func TestClient(t *testing.T) {
             t.Errorf("Expected %v, got %v", expected[i], item)
         }
     }
-} 
\ No newline at end of file
+}
+
+func TestSnapshotIterator(t *testing.T) {
+    aggregate := NewConcreteAggregate()
+    aggregate.AddItem(1)
+    aggregate.AddItem(2)
+
+    iterator := aggregate.CreateSnapshotIterator()
+    aggregate.AddItem(3)
+
+    var result []interface{}
+    for iterator.HasNext() {
+        result = append(result, iterator.Next())
+    }
+    if len(result) != 2 || result[0] != 1 || result[1] != 2 {
+        t.Errorf("Expected snapshot [1 2], got %v", result)
+    }
+    if aggregate.Len() != 3 {
+        t.Errorf("Expected 3 items, got %d", aggregate.Len())
+    }
+}
+
+func TestFailFastIterator(t *testing.T) {
+    aggregate := NewConcreteAggregate()
+    aggregate.AddItem(1)
+    aggregate.AddItem(2)
+
+    iterator := aggregate.CreateFailFastIterator()
+    if item := iterator.Next(); item != 1 {
+        t.Errorf("Expected 1, got %v", item)
+    }
+
+    aggregate.AddItem(3)
+    if iterator.HasNext() {
+        t.Error("Expected HasNext to return false after modification")
+    }
+    if item := iterator.Next(); item != nil {
+        t.Errorf("Expected nil after modification, got %v", item)
+    }
+    if !errors.Is(iterator.Err(), ErrConcurrentModification) {
+        t.Errorf("Expected ErrConcurrentModification, got %v", iterator.Err())
+    }
+
+    // Unmodified iteration runs to the end without an error
+    iterator = aggregate.CreateFailFastIterator()
+    count := 0
+    for iterator.HasNext() {
+        iterator.Next()
+        count++
+    }
+    if count != 3 || iterator.Err() != nil {
+        t.Errorf("Expected 3 items and no error, got %d and %v", count, iterator.Err())
+    }
+}
+
+func TestFailFastIteratorSeq(t *testing.T) {
+    aggregate := NewConcreteAggregate()
+    aggregate.AddItem(1)
+    aggregate.AddItem(2)
+
+    var err error
+    for _, e := range FromIterator[int](aggregate.CreateFailFastIterator()) {
+        if e != nil {
+            err = e
+            break
+        }
+        aggregate.AddItem(0)
+    }
+    if !errors.Is(err, ErrConcurrentModification) {
+        t.Errorf("Expected ErrConcurrentModification from the sequence, got %v", err)
+    }
+}
+
+func TestConcurrentAggregate(t *testing.T) {
+    aggregate := NewConcreteAggregate()
+    var wg sync.WaitGroup
+    for i := 0; i < 4; i++ {
+        wg.Add(2)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < 100; j++ {
+                aggregate.AddItem(j)
+            }
+        }()
+        go func() {
+            defer wg.Done()
+            for j := 0; j < 20; j++ {
+                snapshot := aggregate.CreateSnapshotIterator()
+                for snapshot.HasNext() {
+                    snapshot.Next()
+                }
+                failFast := aggregate.CreateFailFastIterator()
+                for failFast.HasNext() {
+                    failFast.Next()
+                }
+            }
+        }()
+    }
+    wg.Wait()
+
+    if aggregate.Len() != 400 {
+        t.Errorf("Expected 400 items, got %d", aggregate.Len())
+    }
+}
@@ -0,0 +1,180 @@
+package iterator
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strconv"
+	"sync"
+)
+
+// Page is one page of results from a paged source. An empty Next token
+// marks the last page.
+type Page[T any] struct {
+    Items []T
+    Next  string
+}
+
+// FetchFunc fetches the page identified by token. The first page has the empty token.
+type FetchFunc[T any] func(ctx context.Context, token string) (Page[T], error)
+
+// PageError reports a failed page fetch
+type PageError struct {
+    Page  int
+    Token string
+    Err   error
+}
+
+func (e *PageError) Error() string {
+    return fmt.Sprintf("iterator: fetching page %d (token %q): %v", e.Page, e.Token, e.Err)
+}
+
+func (e *PageError) Unwrap() error {
+    return e.Err
+}
+
+// PaginateOption configures Paginate
+type PaginateOption func(*paginateConfig)
+
+type paginateConfig struct {
+    prefetch int
+}
+
+// WithPrefetch fetches up to n pages ahead in the background while the
+// consumer works through the current one. Fetching pauses once n pages are
+// waiting.
+func WithPrefetch(n int) PaginateOption {
+    return func(c *paginateConfig) {
+        c.prefetch = max(n, 0)
+    }
+}
+
+// pageResult is one fetched page, or the error that ended the fetching
+type pageResult[T any] struct {
+    page Page[T]
+    err  error
+}
+
+// Paginate yields the items of a paged source, fetching pages lazily with
+// fetch. A fetch error is yielded as a *PageError and a cancelled ctx as
+// ctx.Err(); either ends the sequence. Breaking out of the loop stops any
+// background fetching before Paginate returns.
+func Paginate[T any](ctx context.Context, fetch FetchFunc[T], opts ...PaginateOption) iter.Seq2[T, error] {
+    var config paginateConfig
+    for _, opt := range opts {
+        opt(&config)
+    }
+
+    return func(yield func(T, error) bool) {
+        ctx, cancel := context.WithCancel(ctx)
+        defer cancel()
+
+        var next func() (pageResult[T], bool)
+        if config.prefetch > 0 {
+            var wg sync.WaitGroup
+            // The fetcher holds one page while it waits to send, so the
+            // buffer takes the rest
+            results := make(chan pageResult[T], config.prefetch-1)
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                defer close(results)
+                fetchPages(ctx, fetch, func(result pageResult[T]) bool {
+                    select {
+                    case results <- result:
+                        return true
+                    case <-ctx.Done():
+                        return false
+                    }
+                })
+            }()
+            // Runs before the deferred cancel above, so stop the fetcher first
+            defer wg.Wait()
+            defer cancel()
+            next = func() (pageResult[T], bool) {
+                result, ok := <-results
+                return result, ok
+            }
+        } else {
+            pull, stop := iter.Pull(func(yield func(pageResult[T]) bool) {
+                fetchPages(ctx, fetch, yield)
+            })
+            defer stop()
+            next = pull
+        }
+
+        var zero T
+        for {
+            result, ok := next()
+            if !ok {
+                if err := ctx.Err(); err != nil {
+                    yield(zero, err)
+                }
+                return
+            }
+            if result.err != nil {
+                yield(zero, result.err)
+                return
+            }
+            for _, item := range result.page.Items {
+                if err := ctx.Err(); err != nil {
+                    yield(zero, err)
+                    return
+                }
+                if !yield(item, nil) {
+                    return
+                }
+            }
+        }
+    }
+}
+
+// fetchPages fetches pages in order and hands each to emit, stopping after
+// the last page, the first error or when emit returns false
+func fetchPages[T any](ctx context.Context, fetch FetchFunc[T], emit func(pageResult[T]) bool) {
+    token := ""
+    for number := 1; ; number++ {
+        if ctx.Err() != nil {
+            return
+        }
+        page, err := fetch(ctx, token)
+        if err != nil {
+            if ctx.Err() == nil {
+                emit(pageResult[T]{err: &PageError{Page: number, Token: token, Err: err}})
+            }
+            return
+        }
+        if !emit(pageResult[T]{page: page}) || page.Next == "" {
+            return
+        }
+        token = page.Next
+    }
+}
+
+// PageNumbers adapts a page/limit source, such as a REST endpoint or a
+// skip/limit database query, to a FetchFunc. Pages are numbered from 1 and a
+// page shorter than limit is the last one. PageNumbers panics if limit is
+// less than 1.
+func PageNumbers[T any](limit int, get func(ctx context.Context, page, limit int) ([]T, error)) FetchFunc[T] {
+    if limit < 1 {
+        panic("iterator: PageNumbers limit must be at least 1")
+    }
+    return func(ctx context.Context, token string) (Page[T], error) {
+        number := 1
+        if token != "" {
+            var err error
+            if number, err = strconv.Atoi(token); err != nil {
+                return Page[T]{}, fmt.Errorf("invalid page token %q: %w", token, err)
+            }
+        }
+        items, err := get(ctx, number, limit)
+        if err != nil {
+            return Page[T]{}, err
+        }
+        page := Page[T]{Items: items}
+        if len(items) >= limit {
+            page.Next = strconv.Itoa(number + 1)
+        }
+        return page, nil
+    }
+}
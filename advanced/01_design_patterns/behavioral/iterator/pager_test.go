@@ -0,0 +1,174 @@
+package iterator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pagedSource serves items 1..total in pages of size, failing at failPage
+type pagedSource struct {
+    total    int
+    size     int
+    failPage int
+    fetches  atomic.Int32
+}
+
+var errSourceDown = errors.New("source down")
+
+func (s *pagedSource) fetch(ctx context.Context, token string) (Page[int], error) {
+    number := 1
+    if token != "" {
+        number, _ = strconv.Atoi(token)
+    }
+    s.fetches.Add(1)
+    if number == s.failPage {
+        return Page[int]{}, errSourceDown
+    }
+    var page Page[int]
+    for i := (number-1)*s.size + 1; i <= min(number*s.size, s.total); i++ {
+        page.Items = append(page.Items, i)
+    }
+    if number*s.size < s.total {
+        page.Next = strconv.Itoa(number + 1)
+    }
+    return page, nil
+}
+
+func TestPaginate(t *testing.T) {
+    for _, prefetch := range []int{0, 1, 3} {
+        t.Run("prefetch="+strconv.Itoa(prefetch), func(t *testing.T) {
+            source := &pagedSource{total: 10, size: 3}
+            got, err := CollectErr(Paginate(context.Background(), source.fetch, WithPrefetch(prefetch)))
+            if err != nil {
+                t.Fatalf("unexpected error: %v", err)
+            }
+            if !slices.Equal(got, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}) {
+                t.Errorf("got %v", got)
+            }
+            if n := source.fetches.Load(); n != 4 {
+                t.Errorf("expected 4 fetches, got %d", n)
+            }
+        })
+    }
+}
+
+func TestPaginateIsLazy(t *testing.T) {
+    source := &pagedSource{total: 10, size: 3}
+    for range Paginate(context.Background(), source.fetch) {
+        break
+    }
+    if n := source.fetches.Load(); n != 1 {
+        t.Errorf("expected 1 fetch, got %d", n)
+    }
+}
+
+func TestPaginateSurfacesFetchErrors(t *testing.T) {
+    for _, prefetch := range []int{0, 2} {
+        source := &pagedSource{total: 10, size: 3, failPage: 2}
+        got, err := CollectErr(Paginate(context.Background(), source.fetch, WithPrefetch(prefetch)))
+
+        var pageErr *PageError
+        if !errors.As(err, &pageErr) || pageErr.Page != 2 || pageErr.Token != "2" {
+            t.Fatalf("prefetch %d: expected PageError for page 2, got %v", prefetch, err)
+        }
+        if !errors.Is(err, errSourceDown) {
+            t.Errorf("prefetch %d: expected errSourceDown in chain", prefetch)
+        }
+        if !slices.Equal(got, []int{1, 2, 3}) {
+            t.Errorf("prefetch %d: expected first page before the error, got %v", prefetch, got)
+        }
+    }
+}
+
+func TestPaginatePrefetchesInBackground(t *testing.T) {
+    for _, prefetch := range []int{1, 2} {
+        source := &pagedSource{total: 15, size: 3}
+        want := int32(1 + prefetch)
+        for item := range Paginate(context.Background(), source.fetch, WithPrefetch(prefetch)) {
+            if item != 1 {
+                continue
+            }
+            deadline := time.Now().Add(time.Second)
+            for source.fetches.Load() < want && time.Now().Before(deadline) {
+                time.Sleep(time.Millisecond)
+            }
+            // Give the fetcher a chance to run further ahead than it should
+            time.Sleep(20 * time.Millisecond)
+            if n := source.fetches.Load(); n != want {
+                t.Errorf("prefetch %d: expected %d fetches while on the first page, got %d", prefetch, want, n)
+            }
+            break
+        }
+    }
+}
+
+func TestPaginateBreakStopsPrefetch(t *testing.T) {
+    stopped := make(chan struct{})
+    fetch := func(ctx context.Context, token string) (Page[int], error) {
+        if token == "" {
+            return Page[int]{Items: []int{1}, Next: "2"}, nil
+        }
+        <-ctx.Done()
+        close(stopped)
+        return Page[int]{}, ctx.Err()
+    }
+
+    for range Paginate(context.Background(), fetch, WithPrefetch(1)) {
+        break
+    }
+    select {
+    case <-stopped:
+    default:
+        t.Error("background fetch still running after Paginate returned")
+    }
+}
+
+func TestPaginateCancellation(t *testing.T) {
+    for _, prefetch := range []int{0, 2} {
+        ctx, cancel := context.WithCancel(context.Background())
+        defer cancel()
+        source := &pagedSource{total: 100, size: 5}
+
+        var got []int
+        var err error
+        for item, e := range Paginate(ctx, source.fetch, WithPrefetch(prefetch)) {
+            if e != nil {
+                err = e
+                break
+            }
+            got = append(got, item)
+            if item == 2 {
+                cancel()
+            }
+        }
+        if !errors.Is(err, context.Canceled) {
+            t.Errorf("prefetch %d: expected context.Canceled, got %v", prefetch, err)
+        }
+        if len(got) != 2 {
+            t.Errorf("prefetch %d: expected iteration to stop after item 2, got %v", prefetch, got)
+        }
+    }
+}
+
+func TestPageNumbers(t *testing.T) {
+    posts := []string{"p1", "p2", "p3", "p4", "p5"}
+    var requested []int
+    fetch := PageNumbers(2, func(ctx context.Context, page, limit int) ([]string, error) {
+        requested = append(requested, page)
+        skip := (page - 1) * limit
+        return posts[min(skip, len(posts)):min(skip+limit, len(posts))], nil
+    })
+
+    got, err := CollectErr(Paginate(context.Background(), fetch))
+    if err != nil || !slices.Equal(got, posts) {
+        t.Errorf("got %v, %v", got, err)
+    }
+    if !slices.Equal(requested, []int{1, 2, 3}) {
+        t.Errorf("expected pages [1 2 3], got %v", requested)
+    }
+}
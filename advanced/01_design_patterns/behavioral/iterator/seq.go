@@ -0,0 +1,260 @@
+package iterator
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+)
+
+// TypeError is yielded when an Iterator element does not have the requested type
+type TypeError struct {
+    Index int
+    Value interface{}
+    Want  string
+}
+
+func (e *TypeError) Error() string {
+    return fmt.Sprintf("iterator: element %d is %T, not %s", e.Index, e.Value, e.Want)
+}
+
+// FromIterator adapts an Iterator to a sequence of T. An element that is not
+// a T is yielded as a *TypeError and iteration continues with the next one.
+// A nil element is T's zero value when T is an interface type.
+// If the Iterator has an Err method, such as FailFastIterator, a non-nil
+// error from it is yielded last. The Iterator is consumed, so the sequence
+// can only be ranged over once.
+func FromIterator[T any](it Iterator) iter.Seq2[T, error] {
+    want := reflect.TypeFor[T]()
+    return func(yield func(T, error) bool) {
+        for index := 0; it.HasNext(); index++ {
+            item := it.Next()
+            value, ok := item.(T)
+            if item == nil && want.Kind() == reflect.Interface {
+                ok = true
+            }
+            var err error
+            if !ok {
+                err = &TypeError{Index: index, Value: item, Want: want.String()}
+            }
+            if !yield(value, err) {
+                return
+            }
+        }
+        if failing, ok := it.(interface{ Err() error }); ok {
+            if err := failing.Err(); err != nil {
+                yield(*new(T), err)
+            }
+        }
+    }
+}
+
+// FromAggregate adapts an Aggregate to a sequence of T. Each range creates a
+// fresh Iterator, so the sequence can be ranged over many times.
+func FromAggregate[T any](aggregate Aggregate) iter.Seq2[T, error] {
+    return func(yield func(T, error) bool) {
+        FromIterator[T](aggregate.CreateIterator())(yield)
+    }
+}
+
+// UntilError yields the values of seq until its first error, which it stores in *err
+func UntilError[T any](seq iter.Seq2[T, error], err *error) iter.Seq[T] {
+    return func(yield func(T) bool) {
+        for value, e := range seq {
+            if e != nil {
+                *err = e
+                return
+            }
+            if !yield(value) {
+                return
+            }
+        }
+    }
+}
+
+// CollectErr collects the values of seq into a slice, stopping at the first error
+func CollectErr[T any](seq iter.Seq2[T, error]) ([]T, error) {
+    var values []T
+    for value, err := range seq {
+        if err != nil {
+            return values, err
+        }
+        values = append(values, value)
+    }
+    return values, nil
+}
+
+// SeqIterator adapts a sequence to the Iterator interface. Elements are
+// pulled from the sequence one at a time, only when HasNext or Next needs
+// them. Call Close if you stop before the end, so the sequence can release
+// its resources.
+type SeqIterator[T any] struct {
+    next    func() (T, bool)
+    stop    func()
+    pending T
+    // pulled is set while pending holds an element not yet returned by Next
+    pulled bool
+    done   bool
+}
+
+// ToIterator adapts seq to the Iterator interface. Nothing is pulled from
+// seq until the first HasNext or Next.
+func ToIterator[T any](seq iter.Seq[T]) *SeqIterator[T] {
+    next, stop := iter.Pull(seq)
+    return &SeqIterator[T]{next: next, stop: stop}
+}
+
+// Next returns the next element, or nil at the end
+func (i *SeqIterator[T]) Next() interface{} {
+    if !i.HasNext() {
+        return nil
+    }
+    i.pulled = false
+    return i.pending
+}
+
+// HasNext checks if there are more elements to iterate
+func (i *SeqIterator[T]) HasNext() bool {
+    if !i.pulled && !i.done {
+        i.pending, i.pulled = i.next()
+        i.done = !i.pulled
+    }
+    return i.pulled
+}
+
+// Close stops the underlying sequence early. It is safe to call more than once.
+func (i *SeqIterator[T]) Close() {
+    i.pulled, i.done = false, true
+    i.stop()
+}
+
+// Map yields f of each value of seq
+func Map[T, U any](seq iter.Seq[T], f func(T) U) iter.Seq[U] {
+    return func(yield func(U) bool) {
+        for value := range seq {
+            if !yield(f(value)) {
+                return
+            }
+        }
+    }
+}
+
+// Filter yields the values of seq for which keep returns true
+func Filter[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+    return func(yield func(T) bool) {
+        for value := range seq {
+            if keep(value) && !yield(value) {
+                return
+            }
+        }
+    }
+}
+
+// Take yields the first n values of seq, then stops it
+func Take[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+    return func(yield func(T) bool) {
+        if n <= 0 {
+            return
+        }
+        taken := 0
+        for value := range seq {
+            if !yield(value) {
+                return
+            }
+            taken++
+            if taken == n {
+                return
+            }
+        }
+    }
+}
+
+// Skip yields the values of seq after the first n
+func Skip[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+    return func(yield func(T) bool) {
+        skipped := 0
+        for value := range seq {
+            if skipped < n {
+                skipped++
+                continue
+            }
+            if !yield(value) {
+                return
+            }
+        }
+    }
+}
+
+// Chunk yields the values of seq in slices of size, the last one possibly
+// shorter. Each slice is newly allocated. Chunk panics if size is less than 1.
+func Chunk[T any](seq iter.Seq[T], size int) iter.Seq[[]T] {
+    if size < 1 {
+        panic("iterator: Chunk size must be at least 1")
+    }
+    return func(yield func([]T) bool) {
+        chunk := make([]T, 0, size)
+        for value := range seq {
+            chunk = append(chunk, value)
+            if len(chunk) == size {
+                if !yield(chunk) {
+                    return
+                }
+                chunk = make([]T, 0, size)
+            }
+        }
+        if len(chunk) > 0 {
+            yield(chunk)
+        }
+    }
+}
+
+// Zip yields pairs of values from a and b, stopping when either ends
+func Zip[A, B any](a iter.Seq[A], b iter.Seq[B]) iter.Seq2[A, B] {
+    return func(yield func(A, B) bool) {
+        nextB, stop := iter.Pull(b)
+        defer stop()
+        for valueA := range a {
+            valueB, ok := nextB()
+            if !ok || !yield(valueA, valueB) {
+                return
+            }
+        }
+    }
+}
+
+// FlatMap yields every value of the sequence f returns for each value of seq
+func FlatMap[T, U any](seq iter.Seq[T], f func(T) iter.Seq[U]) iter.Seq[U] {
+    return func(yield func(U) bool) {
+        for value := range seq {
+            for inner := range f(value) {
+                if !yield(inner) {
+                    return
+                }
+            }
+        }
+    }
+}
+
+// Distinct yields each value of seq the first time it appears
+func Distinct[T comparable](seq iter.Seq[T]) iter.Seq[T] {
+    return func(yield func(T) bool) {
+        seen := make(map[T]struct{})
+        for value := range seq {
+            if _, ok := seen[value]; ok {
+                continue
+            }
+            seen[value] = struct{}{}
+            if !yield(value) {
+                return
+            }
+        }
+    }
+}
+
+// Reduce folds the values of seq into an accumulator, starting from initial
+func Reduce[T, A any](seq iter.Seq[T], initial A, f func(A, T) A) A {
+    acc := initial
+    for value := range seq {
+        acc = f(acc, value)
+    }
+    return acc
+}
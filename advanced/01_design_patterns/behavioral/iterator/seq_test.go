@@ -0,0 +1,242 @@
+package iterator
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+// counting yields 1..n and records whether it ran its cleanup
+func counting(n int, released *bool) iter.Seq[int] {
+    return func(yield func(int) bool) {
+        defer func() { *released = true }()
+        for i := 1; i <= n; i++ {
+            if !yield(i) {
+                return
+            }
+        }
+    }
+}
+
+func TestFromAggregate(t *testing.T) {
+    aggregate := NewConcreteAggregate()
+    aggregate.AddItem("a")
+    aggregate.AddItem("b")
+
+    for range 2 {
+        values, err := CollectErr(FromAggregate[string](aggregate))
+        if err != nil || !slices.Equal(values, []string{"a", "b"}) {
+            t.Errorf("FromAggregate = %v, %v", values, err)
+        }
+    }
+}
+
+func TestFromIteratorTypeError(t *testing.T) {
+    it := NewConcreteIterator([]interface{}{"a", 2, "c"})
+
+    var got []string
+    var typeErr *TypeError
+    for value, err := range FromIterator[string](it) {
+        if err != nil {
+            if !errors.As(err, &typeErr) {
+                t.Fatalf("unexpected error %v", err)
+            }
+            continue
+        }
+        got = append(got, value)
+    }
+    if typeErr == nil || typeErr.Index != 1 {
+        t.Errorf("expected TypeError at index 1, got %v", typeErr)
+    }
+    if !slices.Equal(got, []string{"a", "c"}) {
+        t.Errorf("expected [a c], got %v", got)
+    }
+}
+
+func TestFromIteratorNilElement(t *testing.T) {
+    values, err := CollectErr(FromIterator[any](NewConcreteIterator([]interface{}{"a", nil})))
+    if err != nil || len(values) != 2 || values[1] != nil {
+        t.Errorf("FromIterator[any] = %v, %v", values, err)
+    }
+    errs, err := CollectErr(FromIterator[error](NewConcreteIterator([]interface{}{nil})))
+    if err != nil || len(errs) != 1 || errs[0] != nil {
+        t.Errorf("FromIterator[error] = %v, %v", errs, err)
+    }
+    // A nil is still not a string
+    var typeErr *TypeError
+    if _, err := CollectErr(FromIterator[string](NewConcreteIterator([]interface{}{nil}))); !errors.As(err, &typeErr) || typeErr.Want != "string" {
+        t.Errorf("expected TypeError for nil string, got %v", err)
+    }
+}
+
+func TestUntilError(t *testing.T) {
+    it := NewConcreteIterator([]interface{}{1, 2, "x", 4})
+
+    var err error
+    got := slices.Collect(UntilError(FromIterator[int](it), &err))
+    if !slices.Equal(got, []int{1, 2}) {
+        t.Errorf("expected [1 2], got %v", got)
+    }
+    var typeErr *TypeError
+    if !errors.As(err, &typeErr) {
+        t.Errorf("expected TypeError, got %v", err)
+    }
+}
+
+func TestToIterator(t *testing.T) {
+    released := false
+    it := ToIterator(counting(3, &released))
+
+    var got []interface{}
+    for it.HasNext() {
+        got = append(got, it.Next())
+    }
+    if !slices.Equal(got, []interface{}{1, 2, 3}) {
+        t.Errorf("expected [1 2 3], got %v", got)
+    }
+    if it.Next() != nil {
+        t.Error("expected nil after the end")
+    }
+    if !released {
+        t.Error("sequence was not released at the end")
+    }
+
+    released = false
+    it = ToIterator(counting(3, &released))
+    it.Next()
+    it.Close()
+    it.Close()
+    if !released || it.HasNext() {
+        t.Error("Close did not release the sequence")
+    }
+}
+
+func TestToIteratorIsLazy(t *testing.T) {
+    pulled := 0
+    seq := func(yield func(int) bool) {
+        for i := 1; i <= 2; i++ {
+            pulled++
+            if !yield(i) {
+                return
+            }
+        }
+    }
+    it := ToIterator(seq)
+    defer it.Close()
+    if pulled != 0 {
+        t.Fatalf("expected nothing pulled before HasNext, got %d", pulled)
+    }
+    if !it.HasNext() || !it.HasNext() || pulled != 1 {
+        t.Errorf("expected HasNext to pull once, got %d", pulled)
+    }
+    if it.Next() != 1 || pulled != 1 {
+        t.Errorf("expected Next to return the pulled element, got %d pulls", pulled)
+    }
+    if it.Next() != 2 || pulled != 2 {
+        t.Errorf("expected Next to pull the second element, got %d pulls", pulled)
+    }
+
+    unused := ToIterator(seq)
+    unused.Close()
+    if unused.HasNext() || unused.Next() != nil {
+        t.Error("expected a closed iterator to be empty")
+    }
+}
+
+func TestCombinators(t *testing.T) {
+    released := false
+    numbers := counting(10, &released)
+
+    evens := Filter(numbers, func(n int) bool { return n%2 == 0 })
+    squares := Map(evens, func(n int) int { return n * n })
+    if got := slices.Collect(squares); !slices.Equal(got, []int{4, 16, 36, 64, 100}) {
+        t.Errorf("Map/Filter = %v", got)
+    }
+    if got := slices.Collect(Skip(Take(numbers, 5), 2)); !slices.Equal(got, []int{3, 4, 5}) {
+        t.Errorf("Skip/Take = %v", got)
+    }
+    if got := slices.Collect(Take(numbers, 0)); len(got) != 0 {
+        t.Errorf("Take 0 = %v", got)
+    }
+
+    chunks := slices.Collect(Chunk(Take(numbers, 5), 2))
+    if len(chunks) != 3 || !slices.Equal(chunks[0], []int{1, 2}) || !slices.Equal(chunks[2], []int{5}) {
+        t.Errorf("Chunk = %v", chunks)
+    }
+
+    doubled := FlatMap(Take(numbers, 3), func(n int) iter.Seq[int] {
+        return slices.Values([]int{n, n})
+    })
+    if got := slices.Collect(doubled); !slices.Equal(got, []int{1, 1, 2, 2, 3, 3}) {
+        t.Errorf("FlatMap = %v", got)
+    }
+    if got := slices.Collect(Distinct(doubled)); !slices.Equal(got, []int{1, 2, 3}) {
+        t.Errorf("Distinct = %v", got)
+    }
+
+    sum := Reduce(numbers, 0, func(acc, n int) int { return acc + n })
+    if sum != 55 {
+        t.Errorf("Reduce = %d, want 55", sum)
+    }
+}
+
+func TestZip(t *testing.T) {
+    releasedA, releasedB := false, false
+    letters := slices.Values([]string{"a", "b", "c"})
+
+    var got []string
+    for n, s := range Zip(counting(10, &releasedA), letters) {
+        got = append(got, s+string(rune('0'+n)))
+    }
+    if !slices.Equal(got, []string{"a1", "b2", "c3"}) {
+        t.Errorf("Zip = %v", got)
+    }
+    if !releasedA {
+        t.Error("longer sequence was not released")
+    }
+
+    for range Zip(letters, counting(10, &releasedB)) {
+        break
+    }
+    if !releasedB {
+        t.Error("pulled sequence was not released after break")
+    }
+}
+
+func TestEarlyBreakReleases(t *testing.T) {
+    pipelines := map[string]func(iter.Seq[int]) iter.Seq[int]{
+        "Map":    func(s iter.Seq[int]) iter.Seq[int] { return Map(s, func(n int) int { return n }) },
+        "Filter": func(s iter.Seq[int]) iter.Seq[int] { return Filter(s, func(int) bool { return true }) },
+        "Take":   func(s iter.Seq[int]) iter.Seq[int] { return Take(s, 5) },
+        "Skip":   func(s iter.Seq[int]) iter.Seq[int] { return Skip(s, 1) },
+        "FlatMap": func(s iter.Seq[int]) iter.Seq[int] {
+            return FlatMap(s, func(n int) iter.Seq[int] { return slices.Values([]int{n}) })
+        },
+        "Distinct": func(s iter.Seq[int]) iter.Seq[int] { return Distinct(s) },
+        "Chunk": func(s iter.Seq[int]) iter.Seq[int] {
+            return Map(Chunk(s, 2), func(c []int) int { return c[0] })
+        },
+    }
+
+    for name, pipeline := range pipelines {
+        t.Run(name, func(t *testing.T) {
+            released := false
+            for range pipeline(counting(100, &released)) {
+                break
+            }
+            if !released {
+                t.Error("source was not released after break")
+            }
+        })
+    }
+}
+
+func TestChunkPanicsOnInvalidSize(t *testing.T) {
+    defer func() {
+        if recover() == nil {
+            t.Error("expected panic for size 0")
+        }
+    }()
+    Chunk(slices.Values([]int{1}), 0)
+}
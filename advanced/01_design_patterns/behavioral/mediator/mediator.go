@@ -1,5 +1,7 @@
 package mediator
 
+import "sync"
+
 // Mediator defines the interface for communication between colleagues
 type Mediator interface {
     Notify(sender Colleague, event string)
@@ -12,9 +14,12 @@ type Colleague interface {
     Receive(event string) string
 }
 
-// ConcreteMediator implements the Mediator interface
+// ConcreteMediator implements the Mediator interface. Colleagues receive
+// events in the order they were registered. It is safe for concurrent use.
 type ConcreteMediator struct {
+    mu         sync.RWMutex
     colleagues map[string]Colleague
+    order      []string
 }
 
 // NewConcreteMediator creates a new ConcreteMediator
@@ -24,19 +29,62 @@ func NewConcreteMediator() *ConcreteMediator {
     }
 }
 
-// RegisterColleague registers a colleague with the mediator
+// RegisterColleague registers a colleague with the mediator. Registering a
+// name again replaces the colleague but keeps its place in the order.
 func (m *ConcreteMediator) RegisterColleague(name string, colleague Colleague) {
     colleague.SetMediator(m)
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if _, ok := m.colleagues[name]; !ok {
+        m.order = append(m.order, name)
+    }
     m.colleagues[name] = colleague
 }
 
+// UnregisterColleague removes a colleague, reporting whether it was registered
+func (m *ConcreteMediator) UnregisterColleague(name string) bool {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if _, ok := m.colleagues[name]; !ok {
+        return false
+    }
+    delete(m.colleagues, name)
+    for i, registered := range m.order {
+        if registered == name {
+            m.order = append(m.order[:i:i], m.order[i+1:]...)
+            break
+        }
+    }
+    return true
+}
+
 // Notify handles communication between colleagues
 func (m *ConcreteMediator) Notify(sender Colleague, event string) {
-    for _, colleague := range m.colleagues {
-        if colleague != sender {
-            colleague.Receive(event)
+    m.Gather(sender, event)
+}
+
+// Gather delivers event like Notify and returns each colleague's reply in
+// registration order
+func (m *ConcreteMediator) Gather(sender Colleague, event string) []string {
+    var replies []string
+    for _, colleague := range m.recipients(sender) {
+        replies = append(replies, colleague.Receive(event))
+    }
+    return replies
+}
+
+// recipients returns every colleague except sender, in registration order.
+// Colleagues are called without the lock held, so they may send in turn.
+func (m *ConcreteMediator) recipients(sender Colleague) []Colleague {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    recipients := make([]Colleague, 0, len(m.order))
+    for _, name := range m.order {
+        if colleague := m.colleagues[name]; colleague != sender {
+            recipients = append(recipients, colleague)
         }
     }
+    return recipients
 }
 
 // ConcreteColleagueA implements the Colleague interface
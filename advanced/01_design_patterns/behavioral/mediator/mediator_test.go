@@ -56,4 +56,37 @@ func TestClient(t *testing.T) {
     if result := colleagueB.Receive("test"); result != expected {
         t.Errorf("Expected '%s', got '%s'", expected, result)
     }
-} 
\ No newline at end of file
+}
+
+func TestMediatorOrderAndUnregister(t *testing.T) {
+    mediator := NewConcreteMediator()
+    sender := NewConcreteColleagueA("S")
+    mediator.RegisterColleague("S", sender)
+    for _, name := range []string{"C", "A", "B"} {
+        mediator.RegisterColleague(name, NewConcreteColleagueB(name))
+    }
+
+    expected := []string{"C received: hi", "A received: hi", "B received: hi"}
+    for i := 0; i < 5; i++ {
+        replies := mediator.Gather(sender, "hi")
+        if len(replies) != len(expected) {
+            t.Fatalf("Expected %v, got %v", expected, replies)
+        }
+        for j := range expected {
+            if replies[j] != expected[j] {
+                t.Fatalf("Expected %v, got %v", expected, replies)
+            }
+        }
+    }
+
+    if !mediator.UnregisterColleague("A") {
+        t.Error("Expected A to be unregistered")
+    }
+    if mediator.UnregisterColleague("A") {
+        t.Error("Expected second unregister of A to report false")
+    }
+    replies := mediator.Gather(sender, "hi")
+    if len(replies) != 2 || replies[0] != "C received: hi" || replies[1] != "B received: hi" {
+        t.Errorf("Expected C and B after unregistering A, got %v", replies)
+    }
+}
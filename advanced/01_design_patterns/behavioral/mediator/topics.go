@@ -0,0 +1,403 @@
+package mediator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ErrMediatorClosed is returned when publishing to a closed TopicMediator
+var ErrMediatorClosed = errors.New("mediator: closed")
+
+// ErrUnsubscribed is the reply error for a message that reached an async
+// subscriber after it unsubscribed
+var ErrUnsubscribed = errors.New("mediator: subscriber unsubscribed")
+
+// ErrReentrant is the reply error for a message published to an async
+// subscriber from inside its own handler, directly or through other async
+// handlers. Only that handler drains the mailbox, so the message is not queued.
+var ErrReentrant = errors.New("mediator: published from the subscriber's own handler")
+
+// Topic names a channel of messages of type T whose subscribers reply with R.
+// Topics with the same name but different types are distinct.
+type Topic[T, R any] struct {
+    name string
+}
+
+// NewTopic creates a topic
+func NewTopic[T, R any](name string) Topic[T, R] {
+    return Topic[T, R]{name: name}
+}
+
+// Name returns the topic name
+func (t Topic[T, R]) Name() string {
+    return t.name
+}
+
+// Handler handles a message published on a topic and returns a reply
+type Handler[T, R any] func(ctx context.Context, msg T) (R, error)
+
+// Reply is one subscriber's answer to a published message
+type Reply[R any] struct {
+    Subscriber string
+    Value      R
+    Err        error
+}
+
+// DeliveryMode selects how a subscriber receives messages
+type DeliveryMode int
+
+const (
+    // Sync calls the handler on the publisher's goroutine
+    Sync DeliveryMode = iota
+    // Async queues messages in a mailbox drained by the subscriber's own
+    // goroutine, one message at a time in the order they were queued
+    Async
+)
+
+func (m DeliveryMode) String() string {
+    switch m {
+    case Sync:
+        return "sync"
+    case Async:
+        return "async"
+    default:
+        return fmt.Sprintf("DeliveryMode(%d)", int(m))
+    }
+}
+
+// SubscribeOption configures a subscription
+type SubscribeOption func(*subscriber)
+
+// WithDelivery sets the delivery mode of a subscription. The default is Sync.
+func WithDelivery(mode DeliveryMode) SubscribeOption {
+    return func(s *subscriber) {
+        s.mode = mode
+    }
+}
+
+// WithMailboxSize sets how many messages an Async subscriber buffers before
+// publishers block. The default is 16.
+func WithMailboxSize(size int) SubscribeOption {
+    return func(s *subscriber) {
+        s.mailboxSize = max(size, 0)
+    }
+}
+
+// envelope is a message waiting in an async subscriber's mailbox
+type envelope struct {
+    ctx   context.Context
+    msg   any
+    reply func(value any, err error)
+}
+
+// subscriber is one subscription to a topic
+type subscriber struct {
+    name        string
+    mode        DeliveryMode
+    mailboxSize int
+    handle      func(ctx context.Context, msg any) (any, error)
+
+    // mu guards closed and the senders count. It is never held while a
+    // sender waits for mailbox space, so a handler can unsubscribe itself.
+    mu      sync.RWMutex
+    closed  bool
+    senders sync.WaitGroup
+    quit    chan struct{}
+    mailbox chan envelope
+    done    chan struct{}
+}
+
+// call runs the handler, turning a panic into an error
+func (s *subscriber) call(ctx context.Context, msg any) (value any, err error) {
+    defer func() {
+        if r := recover(); r != nil {
+            err = fmt.Errorf("mediator: subscriber %s panicked: %v", s.name, r)
+        }
+    }()
+    return s.handle(ctx, msg)
+}
+
+// run drains the mailbox until it is closed
+func (s *subscriber) run() {
+    defer close(s.done)
+    for env := range s.mailbox {
+        if err := env.ctx.Err(); err != nil {
+            env.reply(nil, err)
+            continue
+        }
+        ctx := context.WithValue(env.ctx, handlingKey{}, &handling{sub: s, outer: handlingFrom(env.ctx)})
+        env.reply(s.call(ctx, env.msg))
+    }
+}
+
+// handlingKey is the context key for the async subscribers whose handlers
+// led to a publish
+type handlingKey struct{}
+
+// handling is one async subscriber running a handler, and the one whose
+// handler published the message it is handling
+type handling struct {
+    sub   *subscriber
+    outer *handling
+}
+
+func handlingFrom(ctx context.Context) *handling {
+    h, _ := ctx.Value(handlingKey{}).(*handling)
+    return h
+}
+
+// handles reports whether s is running a handler that led to a publish with ctx
+func (s *subscriber) handles(ctx context.Context) bool {
+    for h := handlingFrom(ctx); h != nil; h = h.outer {
+        if h.sub == s {
+            return true
+        }
+    }
+    return false
+}
+
+// enqueue puts a message in the mailbox, blocking while it is full until
+// the subscriber unsubscribes or env.ctx is done
+func (s *subscriber) enqueue(env envelope) error {
+    if s.handles(env.ctx) {
+        return ErrReentrant
+    }
+    s.mu.RLock()
+    if s.closed {
+        s.mu.RUnlock()
+        return ErrUnsubscribed
+    }
+    s.senders.Add(1)
+    s.mu.RUnlock()
+    defer s.senders.Done()
+
+    select {
+    case s.mailbox <- env:
+        return nil
+    case <-s.quit:
+        return ErrUnsubscribed
+    case <-env.ctx.Done():
+        return env.ctx.Err()
+    }
+}
+
+// close stops the mailbox. Blocked senders give up with ErrUnsubscribed, and
+// messages already queued are still delivered.
+func (s *subscriber) close() {
+    if s.mode != Async {
+        return
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.closed {
+        return
+    }
+    s.closed = true
+    close(s.quit)
+    // No sender starts after closed is set, so once the current ones are
+    // gone nothing can send on the mailbox
+    go func() {
+        s.senders.Wait()
+        close(s.mailbox)
+    }()
+}
+
+// Subscription is a subscriber's registration on a topic
+type Subscription struct {
+    mediator *TopicMediator
+    topic    any
+    sub      *subscriber
+}
+
+// Unsubscribe removes the subscription. It is safe to call more than once.
+// An async subscriber still handles the messages already in its mailbox.
+func (s *Subscription) Unsubscribe() {
+    s.mediator.remove(s.topic, s.sub)
+}
+
+// TopicMediator routes typed messages between subscribers by topic. Each
+// message reaches a topic's subscribers in the order they subscribed. It is
+// safe for concurrent use.
+type TopicMediator struct {
+    mu     sync.RWMutex
+    topics map[any][]*subscriber
+    closed bool
+}
+
+// NewTopicMediator creates a new TopicMediator
+func NewTopicMediator() *TopicMediator {
+    return &TopicMediator{topics: make(map[any][]*subscriber)}
+}
+
+// Subscribe registers handler under name for the messages published on topic
+func Subscribe[T, R any](m *TopicMediator, topic Topic[T, R], name string, handler Handler[T, R], opts ...SubscribeOption) (*Subscription, error) {
+    sub := &subscriber{
+        name:        name,
+        mailboxSize: 16,
+        handle: func(ctx context.Context, msg any) (any, error) {
+            return handler(ctx, msg.(T))
+        },
+    }
+    for _, opt := range opts {
+        opt(sub)
+    }
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if m.closed {
+        return nil, ErrMediatorClosed
+    }
+    if sub.mode == Async {
+        sub.mailbox = make(chan envelope, sub.mailboxSize)
+        sub.quit = make(chan struct{})
+        sub.done = make(chan struct{})
+        go sub.run()
+    }
+    // Copy on write, so publishers can range over a slice without the lock
+    m.topics[topic] = append(slices.Clip(m.topics[topic]), sub)
+    return &Subscription{mediator: m, topic: topic, sub: sub}, nil
+}
+
+// Publish delivers msg to every subscriber of topic and returns their replies
+// in subscription order. It waits for async subscribers until they reply or
+// ctx is done; a subscriber that has not replied by then gets ctx.Err().
+// An async handler that publishes should pass on its own ctx, so that its
+// subscriber is skipped with ErrReentrant instead of waiting on itself.
+func Publish[T, R any](ctx context.Context, m *TopicMediator, topic Topic[T, R], msg T) ([]Reply[R], error) {
+    subs, err := m.subscribers(topic)
+    if err != nil {
+        return nil, err
+    }
+
+    type indexed struct {
+        index int
+        value any
+        err   error
+    }
+    replies := make([]Reply[R], len(subs))
+    results := make(chan indexed, len(subs))
+    pending := 0
+    set := func(i int, value any, err error) {
+        replies[i].Err = err
+        if err == nil {
+            replies[i].Value, _ = value.(R)
+        }
+    }
+
+    for i, sub := range subs {
+        replies[i].Subscriber = sub.name
+        if sub.mode == Sync {
+            value, err := sub.call(ctx, msg)
+            set(i, value, err)
+            continue
+        }
+        err := sub.enqueue(envelope{ctx: ctx, msg: msg, reply: func(value any, err error) {
+            results <- indexed{index: i, value: value, err: err}
+        }})
+        if err != nil {
+            set(i, nil, err)
+            continue
+        }
+        pending++
+    }
+
+    answered := make([]bool, len(subs))
+    for ; pending > 0; pending-- {
+        select {
+        case result := <-results:
+            set(result.index, result.value, result.err)
+            answered[result.index] = true
+        case <-ctx.Done():
+            for i, sub := range subs {
+                if sub.mode == Async && !answered[i] && replies[i].Err == nil {
+                    replies[i].Err = ctx.Err()
+                }
+            }
+            return replies, ctx.Err()
+        }
+    }
+    return replies, nil
+}
+
+// Post delivers msg to every subscriber of topic without waiting for
+// replies. Sync subscribers still run before Post returns; async subscribers
+// are only queued, and Post blocks on a full mailbox until there is room or
+// ctx is done. The errors of sync handlers and of messages that could not be
+// queued are returned joined.
+func Post[T, R any](ctx context.Context, m *TopicMediator, topic Topic[T, R], msg T) error {
+    subs, err := m.subscribers(topic)
+    if err != nil {
+        return err
+    }
+    discard := func(any, error) {}
+    var errs []error
+    for _, sub := range subs {
+        if sub.mode == Sync {
+            _, err = sub.call(ctx, msg)
+        } else {
+            err = sub.enqueue(envelope{ctx: ctx, msg: msg, reply: discard})
+        }
+        if err != nil {
+            errs = append(errs, fmt.Errorf("mediator: posting to %s: %w", sub.name, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// Subscribers returns the names subscribed to topic, in delivery order
+func Subscribers[T, R any](m *TopicMediator, topic Topic[T, R]) []string {
+    subs, _ := m.subscribers(topic)
+    names := make([]string, len(subs))
+    for i, sub := range subs {
+        names[i] = sub.name
+    }
+    return names
+}
+
+// Close unsubscribes everyone and waits for async subscribers to finish the
+// messages already in their mailboxes. Calling it from an async handler
+// deadlocks, since it would wait for that handler to return; such a handler
+// should use Unsubscribe.
+func (m *TopicMediator) Close() {
+    m.mu.Lock()
+    topics := m.topics
+    m.topics = make(map[any][]*subscriber)
+    m.closed = true
+    m.mu.Unlock()
+
+    for _, subs := range topics {
+        for _, sub := range subs {
+            sub.close()
+            if sub.done != nil {
+                <-sub.done
+            }
+        }
+    }
+}
+
+func (m *TopicMediator) subscribers(topic any) ([]*subscriber, error) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    if m.closed {
+        return nil, ErrMediatorClosed
+    }
+    return m.topics[topic], nil
+}
+
+func (m *TopicMediator) remove(topic any, sub *subscriber) {
+    m.mu.Lock()
+    subs := m.topics[topic]
+    if i := slices.Index(subs, sub); i >= 0 {
+        subs = slices.Delete(slices.Clone(subs), i, i+1)
+        if len(subs) == 0 {
+            delete(m.topics, topic)
+        } else {
+            m.topics[topic] = subs
+        }
+    }
+    m.mu.Unlock()
+    sub.close()
+}
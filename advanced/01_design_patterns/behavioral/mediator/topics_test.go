@@ -0,0 +1,338 @@
+package mediator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type order struct {
+    ID    int
+    Total float64
+}
+
+var orders = NewTopic[order, string]("orders")
+
+func echo(name string) Handler[order, string] {
+    return func(ctx context.Context, o order) (string, error) {
+        return fmt.Sprintf("%s:%d", name, o.ID), nil
+    }
+}
+
+func replyValues(replies []Reply[string]) []string {
+    values := make([]string, len(replies))
+    for i, reply := range replies {
+        values[i] = reply.Value
+    }
+    return values
+}
+
+func TestPublishSyncOrder(t *testing.T) {
+    m := NewTopicMediator()
+    defer m.Close()
+    for _, name := range []string{"billing", "audit", "shipping"} {
+        if _, err := Subscribe(m, orders, name, echo(name)); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    for i := 0; i < 5; i++ {
+        replies, err := Publish(context.Background(), m, orders, order{ID: i})
+        if err != nil {
+            t.Fatal(err)
+        }
+        want := []string{fmt.Sprintf("billing:%d", i), fmt.Sprintf("audit:%d", i), fmt.Sprintf("shipping:%d", i)}
+        if got := replyValues(replies); !slices.Equal(got, want) {
+            t.Errorf("expected %v, got %v", want, got)
+        }
+    }
+}
+
+func TestTopicsAreTyped(t *testing.T) {
+    m := NewTopicMediator()
+    defer m.Close()
+    counts := NewTopic[int, int]("orders")
+
+    Subscribe(m, orders, "orders", echo("orders"))
+    Subscribe(m, counts, "double", func(ctx context.Context, n int) (int, error) {
+        return n * 2, nil
+    })
+
+    replies, _ := Publish(context.Background(), m, counts, 21)
+    if len(replies) != 1 || replies[0].Value != 42 || replies[0].Subscriber != "double" {
+        t.Errorf("expected only the int subscriber to reply 42, got %+v", replies)
+    }
+    if names := Subscribers(m, orders); !slices.Equal(names, []string{"orders"}) {
+        t.Errorf("expected [orders], got %v", names)
+    }
+}
+
+func TestPublishAsyncCollectsReplies(t *testing.T) {
+    m := NewTopicMediator()
+    defer m.Close()
+    release := make(chan struct{})
+    Subscribe(m, orders, "slow", func(ctx context.Context, o order) (string, error) {
+        <-release
+        return "slow", nil
+    }, WithDelivery(Async))
+    Subscribe(m, orders, "failing", func(ctx context.Context, o order) (string, error) {
+        return "", errors.New("rejected")
+    }, WithDelivery(Async))
+    Subscribe(m, orders, "fast", echo("fast"))
+
+    close(release)
+    replies, err := Publish(context.Background(), m, orders, order{ID: 1})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := replyValues(replies); !slices.Equal(got, []string{"slow", "", "fast:1"}) {
+        t.Errorf("expected replies in subscription order, got %v", got)
+    }
+    if replies[1].Err == nil || replies[1].Subscriber != "failing" {
+        t.Errorf("expected error from failing, got %+v", replies[1])
+    }
+}
+
+func TestPublishTimeout(t *testing.T) {
+    m := NewTopicMediator()
+    release := make(chan struct{})
+    defer m.Close()
+    defer close(release)
+    Subscribe(m, orders, "stuck", func(ctx context.Context, o order) (string, error) {
+        <-release
+        return "late", nil
+    }, WithDelivery(Async))
+    Subscribe(m, orders, "fast", echo("fast"))
+
+    ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+    defer cancel()
+    replies, err := Publish(ctx, m, orders, order{ID: 7})
+    if !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("expected DeadlineExceeded, got %v", err)
+    }
+    if !errors.Is(replies[0].Err, context.DeadlineExceeded) || replies[1].Value != "fast:7" {
+        t.Errorf("unexpected replies %+v", replies)
+    }
+}
+
+func TestAsyncDeliveryOrder(t *testing.T) {
+    m := NewTopicMediator()
+    var mu sync.Mutex
+    var seen []int
+    Subscribe(m, orders, "log", func(ctx context.Context, o order) (string, error) {
+        mu.Lock()
+        seen = append(seen, o.ID)
+        mu.Unlock()
+        return "", nil
+    }, WithDelivery(Async), WithMailboxSize(2))
+
+    for i := 0; i < 50; i++ {
+        if err := Post(context.Background(), m, orders, order{ID: i}); err != nil {
+            t.Fatal(err)
+        }
+    }
+    m.Close()
+
+    if len(seen) != 50 {
+        t.Fatalf("expected 50 deliveries after Close, got %d", len(seen))
+    }
+    for i, id := range seen {
+        if id != i {
+            t.Fatalf("expected publish order, got %v", seen)
+        }
+    }
+    if err := Post(context.Background(), m, orders, order{}); !errors.Is(err, ErrMediatorClosed) {
+        t.Errorf("expected ErrMediatorClosed, got %v", err)
+    }
+}
+
+func TestPostReportsErrors(t *testing.T) {
+    m := NewTopicMediator()
+    defer m.Close()
+    errRejected := errors.New("rejected")
+    Subscribe(m, orders, "failing", func(ctx context.Context, o order) (string, error) {
+        return "", errRejected
+    })
+    release := make(chan struct{})
+    defer close(release)
+    Subscribe(m, orders, "stuck", func(ctx context.Context, o order) (string, error) {
+        <-release
+        return "", nil
+    }, WithDelivery(Async), WithMailboxSize(0))
+
+    // The first message occupies the stuck handler, so the next cannot be queued
+    Post(context.Background(), m, orders, order{ID: 1})
+    ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+    defer cancel()
+    err := Post(ctx, m, orders, order{ID: 2})
+    if !errors.Is(err, errRejected) || !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("expected the handler error and the deadline, got %v", err)
+    }
+    if err == nil || !strings.Contains(err.Error(), "stuck") {
+        t.Errorf("expected the subscriber name in %v", err)
+    }
+}
+
+func TestHandlerUnsubscribesItself(t *testing.T) {
+    m := NewTopicMediator()
+    gate := make(chan struct{})
+    unsubscribed := make(chan struct{})
+    var sub *Subscription
+    var handled sync.WaitGroup
+    handled.Add(1)
+    sub, _ = Subscribe(m, orders, "once", func(ctx context.Context, o order) (string, error) {
+        if o.ID == 0 {
+            <-gate
+            sub.Unsubscribe()
+            close(unsubscribed)
+        } else {
+            handled.Done()
+        }
+        return "", nil
+    }, WithDelivery(Async), WithMailboxSize(1))
+
+    // One message in the handler, one in the mailbox and a third blocked
+    Post(context.Background(), m, orders, order{ID: 0})
+    Post(context.Background(), m, orders, order{ID: 1})
+    blocked := make(chan error)
+    go func() {
+        blocked <- Post(context.Background(), m, orders, order{ID: 2})
+    }()
+    time.Sleep(10 * time.Millisecond)
+    close(gate)
+
+    select {
+    case <-unsubscribed:
+    case <-time.After(time.Second):
+        t.Fatal("Unsubscribe from the handler did not return")
+    }
+    if err := <-blocked; !errors.Is(err, ErrUnsubscribed) {
+        t.Errorf("expected the blocked Post to fail with ErrUnsubscribed, got %v", err)
+    }
+    // The message queued before Unsubscribe is still delivered
+    handled.Wait()
+    m.Close()
+}
+
+func TestPublishFromOwnHandler(t *testing.T) {
+    m := NewTopicMediator()
+    defer m.Close()
+    type inner struct {
+        replies []Reply[string]
+        err     error
+        postErr error
+    }
+    results := make(chan inner, 1)
+    Subscribe(m, orders, "relay", func(ctx context.Context, o order) (string, error) {
+        if o.ID == 1 {
+            replies, err := Publish(ctx, m, orders, order{ID: 2})
+            postErr := Post(ctx, m, orders, order{ID: 3})
+            results <- inner{replies, err, postErr}
+        }
+        return "relayed", nil
+    }, WithDelivery(Async))
+    Subscribe(m, orders, "echo", echo("echo"))
+
+    ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+    defer cancel()
+    if _, err := Publish(ctx, m, orders, order{ID: 1}); err != nil {
+        t.Fatalf("Expected the outer publish to finish, got %v", err)
+    }
+    got := <-results
+    if got.err != nil || !errors.Is(got.replies[0].Err, ErrReentrant) || got.replies[1].Value != "echo:2" {
+        t.Errorf("Expected relay skipped and echo to reply, got %+v, %v", got.replies, got.err)
+    }
+    if !errors.Is(got.postErr, ErrReentrant) {
+        t.Errorf("Expected Post to report ErrReentrant, got %v", got.postErr)
+    }
+}
+
+func TestPublishCycleThroughHandlers(t *testing.T) {
+    m := NewTopicMediator()
+    defer m.Close()
+    audit := NewTopic[order, string]("audit")
+    back := make(chan []Reply[string], 1)
+    Subscribe(m, orders, "a", func(ctx context.Context, o order) (string, error) {
+        Publish(ctx, m, audit, o)
+        return "a", nil
+    }, WithDelivery(Async))
+    Subscribe(m, audit, "b", func(ctx context.Context, o order) (string, error) {
+        // a is waiting for this reply, so publishing back to it must not wait on a
+        replies, _ := Publish(ctx, m, orders, o)
+        back <- replies
+        return "b", nil
+    }, WithDelivery(Async))
+
+    ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+    defer cancel()
+    if _, err := Publish(ctx, m, orders, order{ID: 1}); err != nil {
+        t.Fatalf("Expected the cycle to finish, got %v", err)
+    }
+    if replies := <-back; !errors.Is(replies[0].Err, ErrReentrant) {
+        t.Errorf("Expected ErrReentrant for a, got %+v", replies)
+    }
+}
+
+func TestUnsubscribe(t *testing.T) {
+    m := NewTopicMediator()
+    defer m.Close()
+    Subscribe(m, orders, "a", echo("a"))
+    b, _ := Subscribe(m, orders, "b", echo("b"), WithDelivery(Async))
+    Subscribe(m, orders, "c", echo("c"))
+
+    b.Unsubscribe()
+    b.Unsubscribe()
+
+    replies, err := Publish(context.Background(), m, orders, order{ID: 1})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := replyValues(replies); !slices.Equal(got, []string{"a:1", "c:1"}) {
+        t.Errorf("expected a and c, got %v", got)
+    }
+}
+
+func TestPublishRecoversPanics(t *testing.T) {
+    m := NewTopicMediator()
+    defer m.Close()
+    Subscribe(m, orders, "broken", func(ctx context.Context, o order) (string, error) {
+        panic("boom")
+    })
+    Subscribe(m, orders, "ok", echo("ok"))
+
+    replies, _ := Publish(context.Background(), m, orders, order{ID: 2})
+    if replies[0].Err == nil || !strings.Contains(replies[0].Err.Error(), "boom") {
+        t.Errorf("expected panic error, got %v", replies[0].Err)
+    }
+    if replies[1].Value != "ok:2" {
+        t.Errorf("expected later subscribers to still run, got %+v", replies[1])
+    }
+}
+
+func TestConcurrentPublish(t *testing.T) {
+    m := NewTopicMediator()
+    defer m.Close()
+    Subscribe(m, orders, "async", echo("async"), WithDelivery(Async))
+
+    var wg sync.WaitGroup
+    for i := 0; i < 8; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < 20; j++ {
+                sub, _ := Subscribe(m, orders, "temp", echo("temp"))
+                replies, err := Publish(context.Background(), m, orders, order{ID: j})
+                if err != nil || len(replies) == 0 || replies[0].Value != fmt.Sprintf("async:%d", j) {
+                    t.Errorf("unexpected %+v, %v", replies, err)
+                }
+                sub.Unsubscribe()
+            }
+        }()
+    }
+    wg.Wait()
+}
@@ -1,10 +1,13 @@
 package composite
 
+import "strings"
+
 // Component defines the interface for objects in the composition
 type Component interface {
     Operation() string
     Add(Component)
     Remove(Component)
+    // GetChild returns the child at the index, or nil past the last child
     GetChild(int) Component
 }
 
@@ -50,15 +53,16 @@ func NewComposite(name string) *Composite {
 
 // Operation implements the Component interface
 func (c *Composite) Operation() string {
-    result := "Composite " + c.name + " ["
+    var b strings.Builder
+    b.WriteString("Composite " + c.name + " [")
     for i, child := range c.children {
         if i > 0 {
-            result += ", "
+            b.WriteString(", ")
         }
-        result += child.Operation()
+        b.WriteString(child.Operation())
     }
-    result += "]"
-    return result
+    b.WriteString("]")
+    return b.String()
 }
 
 // Add adds a child component
@@ -82,4 +86,4 @@ func (c *Composite) GetChild(index int) Component {
         return nil
     }
     return c.children[index]
-} 
\ No newline at end of file
+}
\ No newline at end of file
@@ -0,0 +1,186 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// Node is a component visited during a traversal
+type Node struct {
+    // Index is the node's position in pre-order, starting at 0 for the root
+    Index int
+    // Path identifies the node from the root, e.g. "Root/Branch1/A"
+    Path      string
+    Depth     int
+    Component Component
+}
+
+// VisitFunc is called once for every node in the tree
+type VisitFunc[T any] func(ctx context.Context, node Node) (T, error)
+
+// TraverseOptions configures Traverse
+type TraverseOptions struct {
+    // Workers bounds the number of nodes visited at once. Zero means GOMAXPROCS.
+    Workers int
+    // FailFast stops the traversal at the first error instead of visiting every node
+    FailFast bool
+}
+
+// NodeError records which node a VisitFunc failed on
+type NodeError struct {
+    Path string
+    Err  error
+}
+
+// Error implements the error interface
+func (e *NodeError) Error() string {
+    return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *NodeError) Unwrap() error {
+    return e.Err
+}
+
+// Name returns the name of the leaf
+func (l *Leaf) Name() string {
+    return l.name
+}
+
+// Name returns the name of the composite
+func (c *Composite) Name() string {
+    return c.name
+}
+
+// Nodes flattens the tree rooted at root into pre-order.
+// The tree must not be modified while Nodes runs.
+func Nodes(root Component) []Node {
+    var nodes []Node
+    var walk func(c Component, path string, depth int)
+    walk = func(c Component, path string, depth int) {
+        nodes = append(nodes, Node{Index: len(nodes), Path: path, Depth: depth, Component: c})
+        for i, child := range children(c) {
+            walk(child, path+"/"+nodeName(child, i), depth+1)
+        }
+    }
+    if root != nil {
+        walk(root, nodeName(root, 0), 0)
+    }
+    return nodes
+}
+
+// Traverse calls fn for every node of the tree on a bounded pool of workers.
+// Results are returned in pre-order regardless of the order nodes finish in.
+// Failed nodes are reported as *NodeError values joined in pre-order, and
+// their slot in the result slice holds the zero value. If ctx is cancelled,
+// nodes that have not started are skipped and ctx.Err() is included in the
+// returned error.
+//
+// Traverse only reads the tree, so it is safe to run several traversals and
+// Operation calls at the same time as long as nobody calls Add or Remove.
+func Traverse[T any](ctx context.Context, root Component, fn VisitFunc[T], opts TraverseOptions) ([]T, error) {
+    nodes := Nodes(root)
+    results := make([]T, len(nodes))
+    errs := make([]error, len(nodes))
+
+    workers := opts.Workers
+    if workers <= 0 {
+        workers = runtime.GOMAXPROCS(0)
+    }
+    if workers > len(nodes) {
+        workers = len(nodes)
+    }
+
+    parent := ctx
+    ctx, cancel := context.WithCancel(parent)
+    defer cancel()
+
+    jobs := make(chan int)
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range jobs {
+                if ctx.Err() != nil {
+                    continue
+                }
+                result, err := fn(ctx, nodes[i])
+                if err != nil {
+                    errs[i] = &NodeError{Path: nodes[i].Path, Err: err}
+                    if opts.FailFast {
+                        cancel()
+                    }
+                    continue
+                }
+                results[i] = result
+            }
+        }()
+    }
+
+feed:
+    for i := range nodes {
+        select {
+        case jobs <- i:
+        case <-ctx.Done():
+            break feed
+        }
+    }
+    close(jobs)
+    wg.Wait()
+
+    var joined []error
+    for _, err := range errs {
+        if err != nil {
+            joined = append(joined, err)
+        }
+    }
+    // A FailFast cancel is our own doing; only report cancellation from the caller
+    if err := parent.Err(); err != nil {
+        joined = append(joined, err)
+    }
+    return results, errors.Join(joined...)
+}
+
+// ChildCounter is implemented by components that know how many children
+// they have. Traversals use it to bound their GetChild calls, so a component
+// whose GetChild never returns nil still works.
+type ChildCounter interface {
+    ChildCount() int
+}
+
+// children returns the direct children of c. Without a ChildCounter it
+// relies on GetChild returning nil past the last child.
+func children(c Component) []Component {
+    if composite, ok := c.(*Composite); ok {
+        return composite.children
+    }
+    if counter, ok := c.(ChildCounter); ok {
+        result := make([]Component, 0, counter.ChildCount())
+        for i := 0; i < cap(result); i++ {
+            if child := c.GetChild(i); child != nil {
+                result = append(result, child)
+            }
+        }
+        return result
+    }
+    var result []Component
+    for i := 0; ; i++ {
+        child := c.GetChild(i)
+        if child == nil {
+            return result
+        }
+        result = append(result, child)
+    }
+}
+
+// nodeName returns the path segment for c, falling back to its index among its siblings
+func nodeName(c Component, index int) string {
+    if named, ok := c.(interface{ Name() string }); ok {
+        return named.Name()
+    }
+    return strconv.Itoa(index)
+}
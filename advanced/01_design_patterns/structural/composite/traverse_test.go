@@ -0,0 +1,235 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// buildTree creates a tree with the given fan-out and depth below root
+func buildTree(name string, fanOut, depth int) *Composite {
+    root := NewComposite(name)
+    if depth == 0 {
+        return root
+    }
+    for i := 0; i < fanOut; i++ {
+        if depth == 1 {
+            root.Add(NewLeaf(fmt.Sprintf("%s.%d", name, i)))
+        } else {
+            root.Add(buildTree(fmt.Sprintf("%s.%d", name, i), fanOut, depth-1))
+        }
+    }
+    return root
+}
+
+func TestNodes(t *testing.T) {
+    root := NewComposite("Root")
+    branch := NewComposite("Branch")
+    branch.Add(NewLeaf("A"))
+    root.Add(branch)
+    root.Add(NewLeaf("B"))
+
+    expected := []string{"Root", "Root/Branch", "Root/Branch/A", "Root/B"}
+    nodes := Nodes(root)
+    if len(nodes) != len(expected) {
+        t.Fatalf("Expected %d nodes, got %d", len(expected), len(nodes))
+    }
+    for i, node := range nodes {
+        if node.Path != expected[i] || node.Index != i {
+            t.Errorf("Expected node %d at '%s', got %d at '%s'", i, expected[i], node.Index, node.Path)
+        }
+    }
+    if nodes[2].Depth != 2 {
+        t.Errorf("Expected depth 2 for leaf A, got %d", nodes[2].Depth)
+    }
+}
+
+func TestTraverseDeterministicOrder(t *testing.T) {
+    root := buildTree("R", 4, 4)
+    nodes := Nodes(root)
+
+    results, err := Traverse(context.Background(), root, func(ctx context.Context, node Node) (string, error) {
+        // Finish in a different order than the nodes were handed out
+        time.Sleep(time.Duration(len(nodes)-node.Index) * time.Microsecond)
+        return node.Path, nil
+    }, TraverseOptions{Workers: 8})
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+    for i, node := range nodes {
+        if results[i] != node.Path {
+            t.Fatalf("Expected result %d to be '%s', got '%s'", i, node.Path, results[i])
+        }
+    }
+}
+
+func TestTraverseBoundedWorkers(t *testing.T) {
+    root := buildTree("R", 3, 4)
+    var running, peak int64
+
+    _, err := Traverse(context.Background(), root, func(ctx context.Context, node Node) (struct{}, error) {
+        n := atomic.AddInt64(&running, 1)
+        for {
+            p := atomic.LoadInt64(&peak)
+            if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+                break
+            }
+        }
+        time.Sleep(100 * time.Microsecond)
+        atomic.AddInt64(&running, -1)
+        return struct{}{}, nil
+    }, TraverseOptions{Workers: 3})
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+    if peak > 3 {
+        t.Errorf("Expected at most 3 concurrent visits, got %d", peak)
+    }
+}
+
+func TestTraverseCollectsErrors(t *testing.T) {
+    root := NewComposite("Root")
+    root.Add(NewLeaf("A"))
+    root.Add(NewLeaf("B"))
+    root.Add(NewLeaf("C"))
+    errBad := errors.New("bad leaf")
+
+    results, err := Traverse(context.Background(), root, func(ctx context.Context, node Node) (string, error) {
+        if node.Path == "Root/A" || node.Path == "Root/C" {
+            return "", errBad
+        }
+        return node.Path, nil
+    }, TraverseOptions{Workers: 2})
+
+    if !errors.Is(err, errBad) {
+        t.Fatalf("Expected error to wrap errBad, got %v", err)
+    }
+    expected := "Root/A: bad leaf\nRoot/C: bad leaf"
+    if err.Error() != expected {
+        t.Errorf("Expected '%s', got '%s'", expected, err.Error())
+    }
+    var nodeErr *NodeError
+    if !errors.As(err, &nodeErr) || nodeErr.Path != "Root/A" {
+        t.Errorf("Expected first NodeError for 'Root/A', got %v", nodeErr)
+    }
+    if results[2] != "Root/B" || results[1] != "" {
+        t.Errorf("Unexpected results %q", results)
+    }
+}
+
+func TestTraverseFailFast(t *testing.T) {
+    root := buildTree("R", 10, 3)
+    var visited int64
+
+    _, err := Traverse(context.Background(), root, func(ctx context.Context, node Node) (int, error) {
+        atomic.AddInt64(&visited, 1)
+        if node.Index == 5 {
+            return 0, errors.New("boom")
+        }
+        time.Sleep(time.Millisecond)
+        return node.Index, nil
+    }, TraverseOptions{Workers: 2, FailFast: true})
+
+    if err == nil || errors.Is(err, context.Canceled) {
+        t.Fatalf("Expected only the node error, got %v", err)
+    }
+    if total := len(Nodes(root)); visited >= int64(total) {
+        t.Errorf("Expected FailFast to skip nodes, visited %d of %d", visited, total)
+    }
+}
+
+func TestTraverseCancellation(t *testing.T) {
+    root := buildTree("R", 10, 3)
+    ctx, cancel := context.WithCancel(context.Background())
+    var visited int64
+
+    _, err := Traverse(ctx, root, func(ctx context.Context, node Node) (int, error) {
+        if atomic.AddInt64(&visited, 1) == 10 {
+            cancel()
+        }
+        return node.Index, nil
+    }, TraverseOptions{Workers: 1})
+
+    if !errors.Is(err, context.Canceled) {
+        t.Fatalf("Expected context.Canceled, got %v", err)
+    }
+    if visited >= int64(len(Nodes(root))) {
+        t.Errorf("Expected cancellation to stop the traversal, visited %d", visited)
+    }
+}
+
+func TestTraverseEmptyTree(t *testing.T) {
+    results, err := Traverse(context.Background(), nil, func(ctx context.Context, node Node) (int, error) {
+        return 1, nil
+    }, TraverseOptions{})
+    if err != nil || len(results) != 0 {
+        t.Errorf("Expected no results and no error, got %v, %v", results, err)
+    }
+}
+
+func TestTraverseConcurrentReaders(t *testing.T) {
+    root := buildTree("R", 5, 3)
+    expected := root.Operation()
+
+    var wg sync.WaitGroup
+    for i := 0; i < 4; i++ {
+        wg.Add(2)
+        go func() {
+            defer wg.Done()
+            _, err := Traverse(context.Background(), root, func(ctx context.Context, node Node) (string, error) {
+                return node.Component.Operation(), nil
+            }, TraverseOptions{Workers: 4})
+            if err != nil {
+                t.Errorf("Unexpected error: %v", err)
+            }
+        }()
+        go func() {
+            defer wg.Done()
+            if result := root.Operation(); result != expected {
+                t.Errorf("Operation changed during traversal")
+            }
+        }()
+    }
+    wg.Wait()
+}
+
+// ring is a custom component whose GetChild wraps the index, so it never returns nil
+type ring struct {
+    Leaf
+    items []Component
+}
+
+func (r *ring) GetChild(index int) Component {
+    return r.items[index%len(r.items)]
+}
+
+func (r *ring) ChildCount() int {
+    return len(r.items)
+}
+
+func TestTraverseChildCounter(t *testing.T) {
+    root := &ring{Leaf: Leaf{name: "ring"}, items: []Component{NewLeaf("A"), NewLeaf("B")}}
+
+    results, err := Traverse(context.Background(), root, func(ctx context.Context, node Node) (string, error) {
+        return node.Component.Operation(), nil
+    }, TraverseOptions{})
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+    if len(results) != 3 || results[1] != "Leaf A" || results[2] != "Leaf B" {
+        t.Errorf("Expected ring and its two children, got %v", results)
+    }
+}
+
+func BenchmarkTraverse(b *testing.B) {
+    root := buildTree("R", 10, 4)
+    for i := 0; i < b.N; i++ {
+        _, _ = Traverse(context.Background(), root, func(ctx context.Context, node Node) (int, error) {
+            return node.Depth, nil
+        }, TraverseOptions{})
+    }
+}
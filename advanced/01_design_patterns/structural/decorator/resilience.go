@@ -0,0 +1,487 @@
+package decorator
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is returned by TimeoutDecorator when the wrapped call takes too long
+var ErrTimeout = errors.New("decorator: call timed out")
+
+// ErrCircuitOpen is returned by CircuitBreakerDecorator while the circuit rejects calls
+var ErrCircuitOpen = errors.New("decorator: circuit breaker is open")
+
+// ErrBulkheadFull is returned by BulkheadDecorator when no slot frees up in time
+var ErrBulkheadFull = errors.New("decorator: bulkhead is full")
+
+// ContextComponent is the error-returning, context-aware version of Component
+type ContextComponent interface {
+    Operation(ctx context.Context) (string, error)
+}
+
+// ComponentFunc lets an ordinary function act as a ContextComponent
+type ComponentFunc func(ctx context.Context) (string, error)
+
+// Operation implements the ContextComponent interface
+func (f ComponentFunc) Operation(ctx context.Context) (string, error) {
+    return f(ctx)
+}
+
+// FromComponent adapts a Component so it can be wrapped by the resilience decorators
+func FromComponent(component Component) ContextComponent {
+    return ComponentFunc(func(ctx context.Context) (string, error) {
+        if err := ctx.Err(); err != nil {
+            return "", err
+        }
+        return component.Operation(), nil
+    })
+}
+
+// Clock abstracts time so tests can control it
+type Clock interface {
+    Now() time.Time
+    After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the Clock backed by the time package
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func clockOrDefault(clock Clock) Clock {
+    if clock == nil {
+        return SystemClock
+    }
+    return clock
+}
+
+// sleep waits for d on clock, returning early if ctx is done
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+    if d <= 0 {
+        return ctx.Err()
+    }
+    select {
+    case <-clock.After(d):
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// RetryConfig configures a RetryDecorator
+type RetryConfig struct {
+    // MaxAttempts is the total number of calls, including the first. Defaults to 3.
+    MaxAttempts int
+    // BaseDelay is the wait before the first retry
+    BaseDelay time.Duration
+    // MaxDelay caps the wait between attempts. Zero means no cap.
+    MaxDelay time.Duration
+    // Multiplier grows the delay after every retry. Defaults to 2.
+    Multiplier float64
+    // Jitter is the fraction of each delay that is randomised, between 0 and 1
+    Jitter float64
+    // Retryable decides whether an error is worth retrying. Defaults to every
+    // error except context cancellation and ErrCircuitOpen.
+    Retryable func(error) bool
+    // Rand returns values in [0, 1) for jitter. Defaults to math/rand.
+    Rand  func() float64
+    Clock Clock
+}
+
+// RetryStats reports what a RetryDecorator has done so far
+type RetryStats struct {
+    Calls    int
+    Attempts int
+    Retries  int
+    Failures int
+}
+
+// RetryDecorator retries failed calls with exponential backoff and jitter
+type RetryDecorator struct {
+    component ContextComponent
+    config    RetryConfig
+    clock     Clock
+    mu        sync.Mutex
+    stats     RetryStats
+}
+
+// NewRetryDecorator creates a new RetryDecorator
+func NewRetryDecorator(component ContextComponent, config RetryConfig) *RetryDecorator {
+    if config.MaxAttempts <= 0 {
+        config.MaxAttempts = 3
+    }
+    if config.Multiplier <= 0 {
+        config.Multiplier = 2
+    }
+    if config.Retryable == nil {
+        config.Retryable = defaultRetryable
+    }
+    if config.Rand == nil {
+        config.Rand = rand.Float64
+    }
+    return &RetryDecorator{
+        component: component,
+        config:    config,
+        clock:     clockOrDefault(config.Clock),
+    }
+}
+
+func defaultRetryable(err error) bool {
+    return !errors.Is(err, context.Canceled) && !errors.Is(err, ErrCircuitOpen)
+}
+
+// Operation implements the ContextComponent interface
+func (d *RetryDecorator) Operation(ctx context.Context) (string, error) {
+    d.record(func(s *RetryStats) { s.Calls++ })
+    var err error
+    for attempt := 0; attempt < d.config.MaxAttempts; attempt++ {
+        if attempt > 0 {
+            if sleepErr := sleep(ctx, d.clock, d.Backoff(attempt)); sleepErr != nil {
+                // Report the cancellation along with the failure that led to the retry
+                err = errors.Join(sleepErr, err)
+                break
+            }
+            d.record(func(s *RetryStats) { s.Retries++ })
+        }
+        d.record(func(s *RetryStats) { s.Attempts++ })
+        var result string
+        result, err = d.component.Operation(ctx)
+        if err == nil {
+            return result, nil
+        }
+        if !d.config.Retryable(err) || ctx.Err() != nil {
+            break
+        }
+    }
+    d.record(func(s *RetryStats) { s.Failures++ })
+    return "", err
+}
+
+// Backoff returns the delay before the given retry, where 1 is the first retry
+func (d *RetryDecorator) Backoff(retry int) time.Duration {
+    delay := float64(d.config.BaseDelay) * math.Pow(d.config.Multiplier, float64(retry-1))
+    if d.config.MaxDelay > 0 && delay > float64(d.config.MaxDelay) {
+        delay = float64(d.config.MaxDelay)
+    }
+    if d.config.Jitter > 0 {
+        delay -= delay * d.config.Jitter * d.config.Rand()
+    }
+    return time.Duration(delay)
+}
+
+// Stats returns a copy of the decorator's counters
+func (d *RetryDecorator) Stats() RetryStats {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    return d.stats
+}
+
+func (d *RetryDecorator) record(update func(*RetryStats)) {
+    d.mu.Lock()
+    update(&d.stats)
+    d.mu.Unlock()
+}
+
+// TimeoutConfig configures a TimeoutDecorator
+type TimeoutConfig struct {
+    // Timeout bounds each call. Zero or negative means no timeout.
+    Timeout time.Duration
+    Clock   Clock
+}
+
+// TimeoutDecorator fails calls that take longer than the configured timeout.
+// The wrapped call's context is cancelled when the timeout fires.
+type TimeoutDecorator struct {
+    component ContextComponent
+    timeout   time.Duration
+    clock     Clock
+    mu        sync.Mutex
+    timeouts  int
+}
+
+// NewTimeoutDecorator creates a new TimeoutDecorator
+func NewTimeoutDecorator(component ContextComponent, config TimeoutConfig) *TimeoutDecorator {
+    return &TimeoutDecorator{
+        component: component,
+        timeout:   config.Timeout,
+        clock:     clockOrDefault(config.Clock),
+    }
+}
+
+type operationResult struct {
+    value string
+    err   error
+}
+
+// Operation implements the ContextComponent interface
+func (d *TimeoutDecorator) Operation(ctx context.Context) (string, error) {
+    if d.timeout <= 0 {
+        return d.component.Operation(ctx)
+    }
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    done := make(chan operationResult, 1)
+    go func() {
+        value, err := d.component.Operation(ctx)
+        done <- operationResult{value: value, err: err}
+    }()
+
+    select {
+    case result := <-done:
+        return result.value, result.err
+    case <-d.clock.After(d.timeout):
+        d.mu.Lock()
+        d.timeouts++
+        d.mu.Unlock()
+        return "", ErrTimeout
+    case <-ctx.Done():
+        return "", ctx.Err()
+    }
+}
+
+// Timeouts returns how many calls have timed out
+func (d *TimeoutDecorator) Timeouts() int {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    return d.timeouts
+}
+
+// CircuitState is the state of a CircuitBreakerDecorator
+type CircuitState int
+
+const (
+    // StateClosed lets every call through
+    StateClosed CircuitState = iota
+    // StateOpen rejects every call until the open timeout passes
+    StateOpen
+    // StateHalfOpen lets a limited number of probe calls through
+    StateHalfOpen
+)
+
+// String returns the name of the state
+func (s CircuitState) String() string {
+    switch s {
+    case StateClosed:
+        return "closed"
+    case StateOpen:
+        return "open"
+    case StateHalfOpen:
+        return "half-open"
+    default:
+        return "unknown"
+    }
+}
+
+// BreakerConfig configures a CircuitBreakerDecorator
+type BreakerConfig struct {
+    // FailureThreshold is the number of consecutive failures that opens the circuit. Defaults to 5.
+    FailureThreshold int
+    // OpenTimeout is how long the circuit stays open before probing. Defaults to one second.
+    OpenTimeout time.Duration
+    // HalfOpenProbes is the number of successful probes needed to close the
+    // circuit, and the number of probes allowed at once. Defaults to 1.
+    HalfOpenProbes int
+    Clock          Clock
+}
+
+// CircuitBreakerDecorator stops calling a failing component for a while,
+// then lets a few probe calls through to see whether it has recovered
+type CircuitBreakerDecorator struct {
+    component ContextComponent
+    config    BreakerConfig
+    clock     Clock
+    mu        sync.Mutex
+    state     CircuitState
+    failures  int
+    openedAt  time.Time
+    probes    int
+    successes int
+}
+
+// NewCircuitBreakerDecorator creates a new CircuitBreakerDecorator
+func NewCircuitBreakerDecorator(component ContextComponent, config BreakerConfig) *CircuitBreakerDecorator {
+    if config.FailureThreshold <= 0 {
+        config.FailureThreshold = 5
+    }
+    if config.OpenTimeout <= 0 {
+        config.OpenTimeout = time.Second
+    }
+    if config.HalfOpenProbes <= 0 {
+        config.HalfOpenProbes = 1
+    }
+    return &CircuitBreakerDecorator{
+        component: component,
+        config:    config,
+        clock:     clockOrDefault(config.Clock),
+    }
+}
+
+// Operation implements the ContextComponent interface
+func (d *CircuitBreakerDecorator) Operation(ctx context.Context) (string, error) {
+    probe, err := d.acquire()
+    if err != nil {
+        return "", err
+    }
+    result, err := d.component.Operation(ctx)
+    // A caller giving up says nothing about the component's health
+    if err != nil && ctx.Err() != nil {
+        d.release(probe)
+        return result, err
+    }
+    d.recordResult(probe, err)
+    return result, err
+}
+
+// State returns the current state of the circuit
+func (d *CircuitBreakerDecorator) State() CircuitState {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    d.advance()
+    return d.state
+}
+
+// advance moves an open circuit to half-open once its timeout has passed
+func (d *CircuitBreakerDecorator) advance() {
+    if d.state == StateOpen && !d.clock.Now().Before(d.openedAt.Add(d.config.OpenTimeout)) {
+        d.state = StateHalfOpen
+        d.probes = 0
+        d.successes = 0
+    }
+}
+
+func (d *CircuitBreakerDecorator) acquire() (bool, error) {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    d.advance()
+    switch d.state {
+    case StateOpen:
+        return false, ErrCircuitOpen
+    case StateHalfOpen:
+        if d.probes >= d.config.HalfOpenProbes {
+            return false, ErrCircuitOpen
+        }
+        d.probes++
+        return true, nil
+    default:
+        return false, nil
+    }
+}
+
+func (d *CircuitBreakerDecorator) release(probe bool) {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    if probe && d.state == StateHalfOpen {
+        d.probes--
+    }
+}
+
+func (d *CircuitBreakerDecorator) recordResult(probe bool, err error) {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    if probe != (d.state == StateHalfOpen) {
+        // The circuit changed state while the call was running
+        return
+    }
+    if err != nil {
+        d.failures++
+        if d.state == StateHalfOpen || d.failures >= d.config.FailureThreshold {
+            d.trip()
+        }
+        return
+    }
+    if d.state == StateHalfOpen {
+        d.successes++
+        if d.successes >= d.config.HalfOpenProbes {
+            d.state = StateClosed
+        }
+    }
+    d.failures = 0
+}
+
+func (d *CircuitBreakerDecorator) trip() {
+    d.state = StateOpen
+    d.openedAt = d.clock.Now()
+    d.failures = 0
+}
+
+// BulkheadConfig configures a BulkheadDecorator
+type BulkheadConfig struct {
+    // MaxConcurrent is the number of calls allowed at once. Defaults to 1.
+    MaxConcurrent int
+    // MaxWait is how long a call waits for a free slot. Zero rejects immediately.
+    MaxWait time.Duration
+    Clock   Clock
+}
+
+// BulkheadDecorator limits how many calls reach the component at once
+type BulkheadDecorator struct {
+    component ContextComponent
+    slots     chan struct{}
+    maxWait   time.Duration
+    clock     Clock
+    mu        sync.Mutex
+    rejected  int
+}
+
+// NewBulkheadDecorator creates a new BulkheadDecorator
+func NewBulkheadDecorator(component ContextComponent, config BulkheadConfig) *BulkheadDecorator {
+    if config.MaxConcurrent <= 0 {
+        config.MaxConcurrent = 1
+    }
+    return &BulkheadDecorator{
+        component: component,
+        slots:     make(chan struct{}, config.MaxConcurrent),
+        maxWait:   config.MaxWait,
+        clock:     clockOrDefault(config.Clock),
+    }
+}
+
+// Operation implements the ContextComponent interface
+func (d *BulkheadDecorator) Operation(ctx context.Context) (string, error) {
+    if err := d.acquire(ctx); err != nil {
+        return "", err
+    }
+    defer func() { <-d.slots }()
+    return d.component.Operation(ctx)
+}
+
+func (d *BulkheadDecorator) acquire(ctx context.Context) error {
+    select {
+    case d.slots <- struct{}{}:
+        return nil
+    default:
+    }
+    if d.maxWait > 0 {
+        select {
+        case d.slots <- struct{}{}:
+            return nil
+        case <-d.clock.After(d.maxWait):
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+    d.mu.Lock()
+    d.rejected++
+    d.mu.Unlock()
+    return ErrBulkheadFull
+}
+
+// InFlight returns the number of calls currently running
+func (d *BulkheadDecorator) InFlight() int {
+    return len(d.slots)
+}
+
+// Rejected returns how many calls were turned away
+func (d *BulkheadDecorator) Rejected() int {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    return d.rejected
+}
@@ -0,0 +1,414 @@
+package decorator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves when the test calls Advance
+type fakeClock struct {
+    mu      sync.Mutex
+    now     time.Time
+    waiters []fakeWaiter
+    slept   []time.Duration
+}
+
+type fakeWaiter struct {
+    at time.Time
+    ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+    return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    ch := make(chan time.Time, 1)
+    c.slept = append(c.slept, d)
+    c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+    return ch
+}
+
+// Advance moves time forward and fires every timer that is due
+func (c *fakeClock) Advance(d time.Duration) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.now = c.now.Add(d)
+    pending := c.waiters[:0]
+    for _, w := range c.waiters {
+        if !w.at.After(c.now) {
+            w.ch <- c.now
+        } else {
+            pending = append(pending, w)
+        }
+    }
+    c.waiters = pending
+}
+
+// WaitForTimers blocks until n timers are pending
+func (c *fakeClock) WaitForTimers(t *testing.T, n int) {
+    t.Helper()
+    deadline := time.Now().Add(2 * time.Second)
+    for time.Now().Before(deadline) {
+        c.mu.Lock()
+        pending := len(c.waiters)
+        c.mu.Unlock()
+        if pending >= n {
+            return
+        }
+        time.Sleep(time.Millisecond)
+    }
+    t.Fatalf("Timed out waiting for %d timers", n)
+}
+
+// flakyComponent fails the first n calls
+type flakyComponent struct {
+    mu       sync.Mutex
+    failures int
+    calls    int
+}
+
+func (c *flakyComponent) Operation(ctx context.Context) (string, error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.calls++
+    if c.calls <= c.failures {
+        return "", errors.New("flaky failure")
+    }
+    return "ok", nil
+}
+
+func TestFromComponent(t *testing.T) {
+    component := FromComponent(NewConcreteDecoratorA(NewConcreteComponent("A"), "state1"))
+    result, err := component.Operation(context.Background())
+    if err != nil || result != "ConcreteComponent A with ConcreteDecoratorA state1" {
+        t.Errorf("Unexpected result '%s', %v", result, err)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if _, err := component.Operation(ctx); !errors.Is(err, context.Canceled) {
+        t.Errorf("Expected context.Canceled, got %v", err)
+    }
+}
+
+func TestRetryDecoratorBackoff(t *testing.T) {
+    clock := newFakeClock()
+    component := &flakyComponent{failures: 3}
+    retry := NewRetryDecorator(component, RetryConfig{
+        MaxAttempts: 5,
+        BaseDelay:   100 * time.Millisecond,
+        MaxDelay:    300 * time.Millisecond,
+        Clock:       clock,
+    })
+
+    done := make(chan error, 1)
+    go func() {
+        _, err := retry.Operation(context.Background())
+        done <- err
+    }()
+    for i := 0; i < 3; i++ {
+        clock.WaitForTimers(t, 1)
+        clock.Advance(time.Second)
+    }
+    if err := <-done; err != nil {
+        t.Fatalf("Expected success after retries, got %v", err)
+    }
+
+    expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+    for i, d := range expected {
+        if clock.slept[i] != d {
+            t.Errorf("Expected delay %d to be %v, got %v", i, d, clock.slept[i])
+        }
+    }
+    stats := retry.Stats()
+    if stats.Attempts != 4 || stats.Retries != 3 || stats.Failures != 0 {
+        t.Errorf("Unexpected stats %+v", stats)
+    }
+}
+
+func TestRetryDecoratorJitter(t *testing.T) {
+    retry := NewRetryDecorator(&flakyComponent{}, RetryConfig{
+        BaseDelay: 100 * time.Millisecond,
+        Jitter:    0.5,
+        Rand:      func() float64 { return 1 },
+    })
+    if d := retry.Backoff(2); d != 100*time.Millisecond {
+        t.Errorf("Expected jittered delay of 100ms, got %v", d)
+    }
+}
+
+func TestRetryDecoratorGivesUp(t *testing.T) {
+    component := &flakyComponent{failures: 10}
+    retry := NewRetryDecorator(component, RetryConfig{MaxAttempts: 3})
+
+    if _, err := retry.Operation(context.Background()); err == nil {
+        t.Fatal("Expected an error after exhausting attempts")
+    }
+    if component.calls != 3 {
+        t.Errorf("Expected 3 calls, got %d", component.calls)
+    }
+    if stats := retry.Stats(); stats.Failures != 1 {
+        t.Errorf("Expected 1 failure, got %d", stats.Failures)
+    }
+}
+
+func TestRetryDecoratorNonRetryable(t *testing.T) {
+    component := &flakyComponent{failures: 10}
+    retry := NewRetryDecorator(component, RetryConfig{
+        MaxAttempts: 3,
+        Retryable:   func(error) bool { return false },
+    })
+    retry.Operation(context.Background())
+    if component.calls != 1 {
+        t.Errorf("Expected a single call, got %d", component.calls)
+    }
+}
+
+func TestRetryDecoratorCancelledDuringBackoff(t *testing.T) {
+    clock := newFakeClock()
+    component := &flakyComponent{failures: 10}
+    retry := NewRetryDecorator(component, RetryConfig{MaxAttempts: 3, BaseDelay: time.Second, Clock: clock})
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error, 1)
+    go func() {
+        _, err := retry.Operation(ctx)
+        done <- err
+    }()
+    clock.WaitForTimers(t, 1)
+    cancel()
+
+    err := <-done
+    if !errors.Is(err, context.Canceled) {
+        t.Errorf("Expected context.Canceled, got %v", err)
+    }
+    if err == nil || !strings.Contains(err.Error(), "flaky failure") {
+        t.Errorf("Expected the last component error too, got %v", err)
+    }
+}
+
+func TestTimeoutDecorator(t *testing.T) {
+    clock := newFakeClock()
+    cancelled := make(chan struct{})
+    slow := ComponentFunc(func(ctx context.Context) (string, error) {
+        <-ctx.Done()
+        close(cancelled)
+        return "", ctx.Err()
+    })
+    timeout := NewTimeoutDecorator(slow, TimeoutConfig{Timeout: time.Second, Clock: clock})
+
+    done := make(chan error, 1)
+    go func() {
+        _, err := timeout.Operation(context.Background())
+        done <- err
+    }()
+    clock.WaitForTimers(t, 1)
+    clock.Advance(time.Second)
+
+    if err := <-done; !errors.Is(err, ErrTimeout) {
+        t.Errorf("Expected ErrTimeout, got %v", err)
+    }
+    <-cancelled
+    if timeout.Timeouts() != 1 {
+        t.Errorf("Expected 1 timeout, got %d", timeout.Timeouts())
+    }
+}
+
+func TestTimeoutDecoratorFastCall(t *testing.T) {
+    timeout := NewTimeoutDecorator(FromComponent(NewConcreteComponent("A")), TimeoutConfig{
+        Timeout: time.Second,
+        Clock:   newFakeClock(),
+    })
+    if result, err := timeout.Operation(context.Background()); err != nil || result != "ConcreteComponent A" {
+        t.Errorf("Unexpected result '%s', %v", result, err)
+    }
+}
+
+func TestTimeoutDecoratorZeroTimeout(t *testing.T) {
+    clock := newFakeClock()
+    timeout := NewTimeoutDecorator(FromComponent(NewConcreteComponent("A")), TimeoutConfig{Clock: clock})
+    if result, err := timeout.Operation(context.Background()); err != nil || result != "ConcreteComponent A" {
+        t.Errorf("Expected a zero timeout to call through, got '%s', %v", result, err)
+    }
+    if len(clock.slept) != 0 || timeout.Timeouts() != 0 {
+        t.Errorf("Expected no timer and no timeouts, got %v and %d", clock.slept, timeout.Timeouts())
+    }
+}
+
+func TestCircuitBreakerLifecycle(t *testing.T) {
+    clock := newFakeClock()
+    component := &flakyComponent{failures: 3}
+    breaker := NewCircuitBreakerDecorator(component, BreakerConfig{
+        FailureThreshold: 2,
+        OpenTimeout:      time.Minute,
+        Clock:            clock,
+    })
+    ctx := context.Background()
+
+    breaker.Operation(ctx)
+    if breaker.State() != StateClosed {
+        t.Fatalf("Expected closed after one failure, got %s", breaker.State())
+    }
+    breaker.Operation(ctx)
+    if breaker.State() != StateOpen {
+        t.Fatalf("Expected open after two failures, got %s", breaker.State())
+    }
+    if _, err := breaker.Operation(ctx); !errors.Is(err, ErrCircuitOpen) {
+        t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+    }
+    if component.calls != 2 {
+        t.Errorf("Expected open circuit to skip the component, got %d calls", component.calls)
+    }
+
+    // The first probe fails and reopens the circuit
+    clock.Advance(time.Minute)
+    if breaker.State() != StateHalfOpen {
+        t.Fatalf("Expected half-open after timeout, got %s", breaker.State())
+    }
+    breaker.Operation(ctx)
+    if breaker.State() != StateOpen {
+        t.Fatalf("Expected failed probe to reopen, got %s", breaker.State())
+    }
+
+    // The next probe succeeds and closes it
+    clock.Advance(time.Minute)
+    if result, err := breaker.Operation(ctx); err != nil || result != "ok" {
+        t.Fatalf("Expected successful probe, got '%s', %v", result, err)
+    }
+    if breaker.State() != StateClosed {
+        t.Errorf("Expected closed after successful probe, got %s", breaker.State())
+    }
+}
+
+func TestCircuitBreakerLimitsProbes(t *testing.T) {
+    clock := newFakeClock()
+    release := make(chan struct{})
+    started := make(chan struct{})
+    failing := true
+    component := ComponentFunc(func(ctx context.Context) (string, error) {
+        if failing {
+            return "", errors.New("down")
+        }
+        close(started)
+        <-release
+        return "ok", nil
+    })
+    breaker := NewCircuitBreakerDecorator(component, BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second, Clock: clock})
+    breaker.Operation(context.Background())
+    failing = false
+    clock.Advance(time.Second)
+
+    done := make(chan error, 1)
+    go func() {
+        _, err := breaker.Operation(context.Background())
+        done <- err
+    }()
+    <-started
+    if _, err := breaker.Operation(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+        t.Errorf("Expected a second probe to be rejected, got %v", err)
+    }
+    close(release)
+    if err := <-done; err != nil {
+        t.Errorf("Expected probe to succeed, got %v", err)
+    }
+}
+
+func TestBulkheadDecorator(t *testing.T) {
+    release := make(chan struct{})
+    started := make(chan struct{}, 2)
+    component := ComponentFunc(func(ctx context.Context) (string, error) {
+        started <- struct{}{}
+        <-release
+        return "ok", nil
+    })
+    bulkhead := NewBulkheadDecorator(component, BulkheadConfig{MaxConcurrent: 2})
+
+    var wg sync.WaitGroup
+    for i := 0; i < 2; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            bulkhead.Operation(context.Background())
+        }()
+    }
+    <-started
+    <-started
+    if bulkhead.InFlight() != 2 {
+        t.Errorf("Expected 2 calls in flight, got %d", bulkhead.InFlight())
+    }
+    if _, err := bulkhead.Operation(context.Background()); !errors.Is(err, ErrBulkheadFull) {
+        t.Errorf("Expected ErrBulkheadFull, got %v", err)
+    }
+    close(release)
+    wg.Wait()
+    if bulkhead.Rejected() != 1 || bulkhead.InFlight() != 0 {
+        t.Errorf("Expected 1 rejection and nothing in flight, got %d and %d", bulkhead.Rejected(), bulkhead.InFlight())
+    }
+}
+
+func TestBulkheadDecoratorWaits(t *testing.T) {
+    clock := newFakeClock()
+    release := make(chan struct{})
+    component := ComponentFunc(func(ctx context.Context) (string, error) {
+        <-release
+        return "ok", nil
+    })
+    bulkhead := NewBulkheadDecorator(component, BulkheadConfig{MaxConcurrent: 1, MaxWait: time.Second, Clock: clock})
+
+    go bulkhead.Operation(context.Background())
+    for bulkhead.InFlight() == 0 {
+        time.Sleep(time.Millisecond)
+    }
+    done := make(chan error, 1)
+    go func() {
+        _, err := bulkhead.Operation(context.Background())
+        done <- err
+    }()
+    clock.WaitForTimers(t, 1)
+    close(release)
+    if err := <-done; err != nil {
+        t.Errorf("Expected waiting call to get a slot, got %v", err)
+    }
+}
+
+func TestResilienceDecoratorsStack(t *testing.T) {
+    component := &flakyComponent{failures: 2}
+    breaker := NewCircuitBreakerDecorator(component, BreakerConfig{FailureThreshold: 5})
+    stack := NewRetryDecorator(
+        NewTimeoutDecorator(
+            NewBulkheadDecorator(breaker, BulkheadConfig{MaxConcurrent: 1}),
+            TimeoutConfig{Timeout: time.Second},
+        ),
+        RetryConfig{MaxAttempts: 3},
+    )
+
+    result, err := stack.Operation(context.Background())
+    if err != nil || result != "ok" {
+        t.Fatalf("Expected the stack to recover, got '%s', %v", result, err)
+    }
+    if breaker.State() != StateClosed {
+        t.Errorf("Expected closed circuit, got %s", breaker.State())
+    }
+
+    // The same decorators in a different order
+    reordered := NewCircuitBreakerDecorator(
+        NewRetryDecorator(&flakyComponent{failures: 1}, RetryConfig{MaxAttempts: 2}),
+        BreakerConfig{FailureThreshold: 1},
+    )
+    if _, err := reordered.Operation(context.Background()); err != nil {
+        t.Errorf("Expected retries inside the breaker to hide the failure, got %v", err)
+    }
+}
@@ -0,0 +1,358 @@
+package decorator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLayerExists is returned when a stack already has a layer with the given name
+var ErrLayerExists = errors.New("decorator: layer already exists")
+
+// ErrLayerNotFound is returned when a stack has no layer with the given name
+var ErrLayerNotFound = errors.New("decorator: layer not found")
+
+// ErrUnknownDecorator is returned when a config names a decorator type missing from the registry
+var ErrUnknownDecorator = errors.New("decorator: unknown decorator type")
+
+// ErrUnknownParam is returned when a layer config has a param its decorator does not take
+var ErrUnknownParam = errors.New("decorator: unknown param")
+
+// ErrMissingParam is returned when a layer config leaves out a required param
+var ErrMissingParam = errors.New("decorator: missing param")
+
+// WrapFunc wraps a component with one decorator
+type WrapFunc[C any] func(inner C) C
+
+// Layer is one named decorator in a Stack
+type Layer[C any] struct {
+    Name string
+    Wrap WrapFunc[C]
+}
+
+// Stack records the decorators around a base component by name, so they can be
+// listed and rearranged before the decorated component is built. The first layer
+// wraps the base component directly and the last layer is the outermost one.
+// C is the component type, e.g. Component or ContextComponent.
+type Stack[C any] struct {
+    base   C
+    layers []Layer[C]
+}
+
+// NewStack creates a new Stack with no layers
+func NewStack[C any](base C) *Stack[C] {
+    return &Stack[C]{base: base}
+}
+
+// Push adds a layer on the outside of the stack
+func (s *Stack[C]) Push(name string, wrap WrapFunc[C]) error {
+    return s.insert(len(s.layers), name, wrap)
+}
+
+// InsertBefore adds a layer just inside the named layer, so the named layer wraps it
+func (s *Stack[C]) InsertBefore(target, name string, wrap WrapFunc[C]) error {
+    i, err := s.index(target)
+    if err != nil {
+        return err
+    }
+    return s.insert(i, name, wrap)
+}
+
+// InsertAfter adds a layer just outside the named layer, so it wraps the named layer
+func (s *Stack[C]) InsertAfter(target, name string, wrap WrapFunc[C]) error {
+    i, err := s.index(target)
+    if err != nil {
+        return err
+    }
+    return s.insert(i+1, name, wrap)
+}
+
+// Remove removes the named layer
+func (s *Stack[C]) Remove(name string) error {
+    i, err := s.index(name)
+    if err != nil {
+        return err
+    }
+    s.layers = append(s.layers[:i], s.layers[i+1:]...)
+    return nil
+}
+
+// Layers returns the layer names from the innermost to the outermost
+func (s *Stack[C]) Layers() []string {
+    names := make([]string, len(s.layers))
+    for i, layer := range s.layers {
+        names[i] = layer.Name
+    }
+    return names
+}
+
+// Build wraps the base component with every layer and returns the result
+func (s *Stack[C]) Build() C {
+    component := s.base
+    for _, layer := range s.layers {
+        component = layer.Wrap(component)
+    }
+    return component
+}
+
+func (s *Stack[C]) index(name string) (int, error) {
+    for i, layer := range s.layers {
+        if layer.Name == name {
+            return i, nil
+        }
+    }
+    return 0, fmt.Errorf("%w: %q", ErrLayerNotFound, name)
+}
+
+func (s *Stack[C]) insert(i int, name string, wrap WrapFunc[C]) error {
+    if _, err := s.index(name); err == nil {
+        return fmt.Errorf("%w: %q", ErrLayerExists, name)
+    }
+    s.layers = append(s.layers, Layer[C]{})
+    copy(s.layers[i+1:], s.layers[i:])
+    s.layers[i] = Layer[C]{Name: name, Wrap: wrap}
+    return nil
+}
+
+// LayerConfig declares one layer of a stack
+type LayerConfig struct {
+    // Name identifies the layer in the stack. Defaults to Type.
+    Name string `json:"name,omitempty"`
+    // Type selects the factory in the registry
+    Type   string            `json:"type"`
+    Params map[string]string `json:"params,omitempty"`
+}
+
+// StackConfig declares the layers of a stack from the innermost to the outermost
+type StackConfig struct {
+    Layers []LayerConfig `json:"layers"`
+}
+
+// Factory creates the wrap function for one layer from its params
+type Factory[C any] func(params map[string]string) (WrapFunc[C], error)
+
+// Registry maps decorator type names to factories
+type Registry[C any] struct {
+    factories map[string]Factory[C]
+}
+
+// NewRegistry creates a new, empty Registry
+func NewRegistry[C any]() *Registry[C] {
+    return &Registry[C]{factories: make(map[string]Factory[C])}
+}
+
+// Register adds a factory under the given type name, replacing any previous one
+func (r *Registry[C]) Register(decoratorType string, factory Factory[C]) {
+    r.factories[decoratorType] = factory
+}
+
+// NewStackFromConfig builds a stack around base from a declarative config
+func NewStackFromConfig[C any](base C, config StackConfig, registry *Registry[C]) (*Stack[C], error) {
+    if registry == nil {
+        return nil, errors.New("decorator: nil registry")
+    }
+    stack := NewStack(base)
+    for _, layer := range config.Layers {
+        factory, ok := registry.factories[layer.Type]
+        if !ok {
+            return nil, fmt.Errorf("%w: %q", ErrUnknownDecorator, layer.Type)
+        }
+        wrap, err := factory(layer.Params)
+        if err != nil {
+            return nil, fmt.Errorf("decorator: layer %q: %w", layer.Type, err)
+        }
+        name := layer.Name
+        if name == "" {
+            name = layer.Type
+        }
+        if err := stack.Push(name, wrap); err != nil {
+            return nil, err
+        }
+    }
+    return stack, nil
+}
+
+// NewComponentRegistry returns a registry of the string decorators:
+// "stateA" (param "state") and "brackets" (params "open" and "close")
+func NewComponentRegistry() *Registry[Component] {
+    registry := NewRegistry[Component]()
+    registry.Register("stateA", func(params map[string]string) (WrapFunc[Component], error) {
+        p := newParamParser(params)
+        state := p.string("state")
+        if err := p.finish(); err != nil {
+            return nil, err
+        }
+        return func(inner Component) Component {
+            return NewConcreteDecoratorA(inner, state)
+        }, nil
+    })
+    registry.Register("brackets", func(params map[string]string) (WrapFunc[Component], error) {
+        p := newParamParser(params)
+        prefix, suffix := p.string("open"), p.string("close")
+        if err := p.finish(); err != nil {
+            return nil, err
+        }
+        if prefix == "" && suffix == "" {
+            prefix, suffix = "[", "]"
+        }
+        return func(inner Component) Component {
+            return NewConcreteDecoratorB(inner, func(s string) string {
+                return prefix + s + suffix
+            })
+        }, nil
+    })
+    return registry
+}
+
+// NewResilienceRegistry returns a registry of the resilience decorators under
+// the types "retry", "timeout", "circuit_breaker" and "bulkhead". Params use
+// the config field names in lower camel case, with durations such as "250ms".
+// "timeout" requires its "timeout" param; the other params are optional and
+// unknown params are rejected. Every decorator uses the given clock.
+func NewResilienceRegistry(clock Clock) *Registry[ContextComponent] {
+    registry := NewRegistry[ContextComponent]()
+    registry.Register("retry", func(params map[string]string) (WrapFunc[ContextComponent], error) {
+        p := newParamParser(params)
+        config := RetryConfig{
+            MaxAttempts: p.int("maxAttempts"),
+            BaseDelay:   p.duration("baseDelay"),
+            MaxDelay:    p.duration("maxDelay"),
+            Multiplier:  p.float("multiplier"),
+            Jitter:      p.float("jitter"),
+            Clock:       clock,
+        }
+        if err := p.finish(); err != nil {
+            return nil, err
+        }
+        return func(inner ContextComponent) ContextComponent {
+            return NewRetryDecorator(inner, config)
+        }, nil
+    })
+    registry.Register("timeout", func(params map[string]string) (WrapFunc[ContextComponent], error) {
+        p := newParamParser(params)
+        p.require("timeout")
+        config := TimeoutConfig{Timeout: p.duration("timeout"), Clock: clock}
+        if err := p.finish(); err != nil {
+            return nil, err
+        }
+        return func(inner ContextComponent) ContextComponent {
+            return NewTimeoutDecorator(inner, config)
+        }, nil
+    })
+    registry.Register("circuit_breaker", func(params map[string]string) (WrapFunc[ContextComponent], error) {
+        p := newParamParser(params)
+        config := BreakerConfig{
+            FailureThreshold: p.int("failureThreshold"),
+            OpenTimeout:      p.duration("openTimeout"),
+            HalfOpenProbes:   p.int("halfOpenProbes"),
+            Clock:            clock,
+        }
+        if err := p.finish(); err != nil {
+            return nil, err
+        }
+        return func(inner ContextComponent) ContextComponent {
+            return NewCircuitBreakerDecorator(inner, config)
+        }, nil
+    })
+    registry.Register("bulkhead", func(params map[string]string) (WrapFunc[ContextComponent], error) {
+        p := newParamParser(params)
+        config := BulkheadConfig{
+            MaxConcurrent: p.int("maxConcurrent"),
+            MaxWait:       p.duration("maxWait"),
+            Clock:         clock,
+        }
+        if err := p.finish(); err != nil {
+            return nil, err
+        }
+        return func(inner ContextComponent) ContextComponent {
+            return NewBulkheadDecorator(inner, config)
+        }, nil
+    })
+    return registry
+}
+
+// paramParser reads typed values from layer params, keeping the first error.
+// It records which keys were read, so finish can reject the rest.
+type paramParser struct {
+    params map[string]string
+    used   map[string]bool
+    err    error
+}
+
+func newParamParser(params map[string]string) *paramParser {
+    return &paramParser{params: params, used: make(map[string]bool)}
+}
+
+// require records an error unless every key is present
+func (p *paramParser) require(keys ...string) {
+    for _, key := range keys {
+        if _, ok := p.params[key]; !ok && p.err == nil {
+            p.err = fmt.Errorf("%w: %q", ErrMissingParam, key)
+        }
+    }
+}
+
+// finish returns the first parse error, or an error naming a param that was never read
+func (p *paramParser) finish() error {
+    if p.err != nil {
+        return p.err
+    }
+    var unknown []string
+    for key := range p.params {
+        if !p.used[key] {
+            unknown = append(unknown, strconv.Quote(key))
+        }
+    }
+    if len(unknown) > 0 {
+        slices.Sort(unknown)
+        return fmt.Errorf("%w: %s", ErrUnknownParam, strings.Join(unknown, ", "))
+    }
+    return nil
+}
+
+func (p *paramParser) string(key string) string {
+    p.used[key] = true
+    return p.params[key]
+}
+
+func (p *paramParser) int(key string) int {
+    p.used[key] = true
+    value, ok := p.params[key]
+    if !ok || p.err != nil {
+        return 0
+    }
+    n, err := strconv.Atoi(value)
+    if err != nil {
+        p.err = fmt.Errorf("param %q: %w", key, err)
+    }
+    return n
+}
+
+func (p *paramParser) float(key string) float64 {
+    p.used[key] = true
+    value, ok := p.params[key]
+    if !ok || p.err != nil {
+        return 0
+    }
+    f, err := strconv.ParseFloat(value, 64)
+    if err != nil {
+        p.err = fmt.Errorf("param %q: %w", key, err)
+    }
+    return f
+}
+
+func (p *paramParser) duration(key string) time.Duration {
+    p.used[key] = true
+    value, ok := p.params[key]
+    if !ok || p.err != nil {
+        return 0
+    }
+    d, err := time.ParseDuration(value)
+    if err != nil {
+        p.err = fmt.Errorf("param %q: %w", key, err)
+    }
+    return d
+}
@@ -0,0 +1,158 @@
+package decorator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tag returns a wrap function that appends a marker to the component's output
+func tag(marker string) WrapFunc[Component] {
+    return func(inner Component) Component {
+        return NewConcreteDecoratorB(inner, func(s string) string {
+            return s + " " + marker
+        })
+    }
+}
+
+func TestStackBuild(t *testing.T) {
+    stack := NewStack[Component](NewConcreteComponent("A"))
+    stack.Push("first", tag("1"))
+    stack.Push("second", tag("2"))
+
+    if layers := stack.Layers(); !reflect.DeepEqual(layers, []string{"first", "second"}) {
+        t.Errorf("Unexpected layers %v", layers)
+    }
+    expected := "ConcreteComponent A 1 2"
+    if result := stack.Build().Operation(); result != expected {
+        t.Errorf("Expected '%s', got '%s'", expected, result)
+    }
+}
+
+func TestStackInsertAndRemove(t *testing.T) {
+    stack := NewStack[Component](NewConcreteComponent("A"))
+    stack.Push("first", tag("1"))
+    stack.Push("last", tag("3"))
+
+    if err := stack.InsertAfter("first", "middle", tag("2")); err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+    if err := stack.InsertBefore("first", "innermost", tag("0")); err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+    expected := []string{"innermost", "first", "middle", "last"}
+    if layers := stack.Layers(); !reflect.DeepEqual(layers, expected) {
+        t.Errorf("Expected layers %v, got %v", expected, layers)
+    }
+    if result := stack.Build().Operation(); result != "ConcreteComponent A 0 1 2 3" {
+        t.Errorf("Unexpected result '%s'", result)
+    }
+
+    if err := stack.Remove("middle"); err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+    if result := stack.Build().Operation(); result != "ConcreteComponent A 0 1 3" {
+        t.Errorf("Unexpected result after remove '%s'", result)
+    }
+}
+
+func TestStackErrors(t *testing.T) {
+    stack := NewStack[Component](NewConcreteComponent("A"))
+    stack.Push("first", tag("1"))
+
+    if err := stack.Push("first", tag("1")); !errors.Is(err, ErrLayerExists) {
+        t.Errorf("Expected ErrLayerExists, got %v", err)
+    }
+    if err := stack.Remove("missing"); !errors.Is(err, ErrLayerNotFound) {
+        t.Errorf("Expected ErrLayerNotFound, got %v", err)
+    }
+    if err := stack.InsertAfter("missing", "x", tag("x")); !errors.Is(err, ErrLayerNotFound) {
+        t.Errorf("Expected ErrLayerNotFound, got %v", err)
+    }
+}
+
+func TestStackFromConfig(t *testing.T) {
+    var config StackConfig
+    err := json.Unmarshal([]byte(`{
+        "layers": [
+            {"type": "stateA", "params": {"state": "dev"}},
+            {"name": "outer", "type": "brackets", "params": {"open": "{", "close": "}"}}
+        ]
+    }`), &config)
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+
+    stack, err := NewStackFromConfig[Component](NewConcreteComponent("A"), config, NewComponentRegistry())
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+    if layers := stack.Layers(); !reflect.DeepEqual(layers, []string{"stateA", "outer"}) {
+        t.Errorf("Unexpected layers %v", layers)
+    }
+    expected := "{ConcreteComponent A with ConcreteDecoratorA dev}"
+    if result := stack.Build().Operation(); result != expected {
+        t.Errorf("Expected '%s', got '%s'", expected, result)
+    }
+}
+
+func TestStackFromConfigUnknownType(t *testing.T) {
+    config := StackConfig{Layers: []LayerConfig{{Type: "missing"}}}
+    _, err := NewStackFromConfig[Component](NewConcreteComponent("A"), config, NewComponentRegistry())
+    if !errors.Is(err, ErrUnknownDecorator) {
+        t.Errorf("Expected ErrUnknownDecorator, got %v", err)
+    }
+}
+
+func TestResilienceRegistry(t *testing.T) {
+    config := StackConfig{Layers: []LayerConfig{
+        {Type: "circuit_breaker", Params: map[string]string{"failureThreshold": "3", "openTimeout": "1m"}},
+        {Type: "bulkhead", Params: map[string]string{"maxConcurrent": "4"}},
+        {Type: "timeout", Params: map[string]string{"timeout": "1s"}},
+        {Type: "retry", Params: map[string]string{"maxAttempts": "3", "baseDelay": "0s"}},
+    }}
+    component := &flakyComponent{failures: 2}
+
+    stack, err := NewStackFromConfig[ContextComponent](component, config, NewResilienceRegistry(nil))
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+    result, err := stack.Build().Operation(context.Background())
+    if err != nil || result != "ok" {
+        t.Errorf("Expected 'ok', got '%s', %v", result, err)
+    }
+
+    config.Layers[2].Params["timeout"] = "soon"
+    if _, err := NewStackFromConfig[ContextComponent](component, config, NewResilienceRegistry(nil)); err == nil {
+        t.Error("Expected an error for an invalid duration")
+    }
+}
+
+func TestStackFromConfigValidatesParams(t *testing.T) {
+    registry := NewResilienceRegistry(nil)
+    base := ContextComponent(&flakyComponent{})
+    build := func(layer LayerConfig) error {
+        _, err := NewStackFromConfig(base, StackConfig{Layers: []LayerConfig{layer}}, registry)
+        return err
+    }
+
+    if err := build(LayerConfig{Type: "timeout"}); !errors.Is(err, ErrMissingParam) {
+        t.Errorf("Expected ErrMissingParam for a timeout without a duration, got %v", err)
+    }
+    err := build(LayerConfig{Type: "retry", Params: map[string]string{"maxAttempt": "3"}})
+    if !errors.Is(err, ErrUnknownParam) || !strings.Contains(err.Error(), "maxAttempt") {
+        t.Errorf("Expected ErrUnknownParam naming maxAttempt, got %v", err)
+    }
+    _, err = NewStackFromConfig[Component](NewConcreteComponent("A"), StackConfig{Layers: []LayerConfig{
+        {Type: "brackets", Params: map[string]string{"opn": "<"}},
+    }}, NewComponentRegistry())
+    if !errors.Is(err, ErrUnknownParam) {
+        t.Errorf("Expected ErrUnknownParam for brackets, got %v", err)
+    }
+    if _, err := NewStackFromConfig[ContextComponent](base, StackConfig{}, nil); err == nil {
+        t.Error("Expected an error for a nil registry")
+    }
+}
@@ -1,5 +1,11 @@
 package facade
 
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
 // SubsystemA represents a complex subsystem
 type SubsystemA struct {
     name string
@@ -15,6 +21,11 @@ func (s *SubsystemA) OperationA() string {
     return "SubsystemA " + s.name + " operation"
 }
 
+// Call implements the Subsystem interface
+func (s *SubsystemA) Call(ctx context.Context) (string, error) {
+    return s.OperationA(), nil
+}
+
 // SubsystemB represents another complex subsystem
 type SubsystemB struct {
     name string
@@ -30,6 +41,11 @@ func (s *SubsystemB) OperationB() string {
     return "SubsystemB " + s.name + " operation"
 }
 
+// Call implements the Subsystem interface
+func (s *SubsystemB) Call(ctx context.Context) (string, error) {
+    return s.OperationB(), nil
+}
+
 // SubsystemC represents yet another complex subsystem
 type SubsystemC struct {
     name string
@@ -45,29 +61,41 @@ func (s *SubsystemC) OperationC() string {
     return "SubsystemC " + s.name + " operation"
 }
 
+// Call implements the Subsystem interface
+func (s *SubsystemC) Call(ctx context.Context) (string, error) {
+    return s.OperationC(), nil
+}
+
 // Facade provides a simplified interface to the complex subsystems
 type Facade struct {
-    subsystemA *SubsystemA
-    subsystemB *SubsystemB
-    subsystemC *SubsystemC
+    subsystems []SubsystemSpec
+    policy     Policy
+    mu         sync.Mutex
+    running    bool
+    started    []SubsystemSpec
 }
 
 // NewFacade creates a new Facade
 func NewFacade(name string) *Facade {
-    return &Facade{
-        subsystemA: NewSubsystemA(name + "_A"),
-        subsystemB: NewSubsystemB(name + "_B"),
-        subsystemC: NewSubsystemC(name + "_C"),
-    }
+    return NewFacadeWithSubsystems(RequireAll(),
+        SubsystemSpec{Name: "A", Subsystem: NewSubsystemA(name + "_A")},
+        SubsystemSpec{Name: "B", Subsystem: NewSubsystemB(name + "_B")},
+        SubsystemSpec{Name: "C", Subsystem: NewSubsystemC(name + "_C")},
+    )
 }
 
 // Operation provides a simplified interface to the complex subsystems
 func (f *Facade) Operation() string {
-    result := "Facade operation:\n"
-    result += "- " + f.subsystemA.OperationA() + "\n"
-    result += "- " + f.subsystemB.OperationB() + "\n"
-    result += "- " + f.subsystemC.OperationC()
-    return result
+    result, _ := f.Execute(context.Background())
+    lines := []string{"Facade operation:"}
+    for _, r := range result.Subsystems {
+        if r.Err != nil {
+            lines = append(lines, "- "+r.Name+" failed: "+r.Err.Error())
+        } else {
+            lines = append(lines, "- "+r.Output)
+        }
+    }
+    return strings.Join(lines, "\n")
 }
 
 // Client represents a client that uses the Facade
@@ -0,0 +1,231 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrAlreadyStarted is returned by Start when the facade is already running
+var ErrAlreadyStarted = errors.New("facade: already started")
+
+// ErrNotStarted is returned by Stop when the facade is not running
+var ErrNotStarted = errors.New("facade: not started")
+
+// ErrDependencyCycle is returned by Start when subsystems depend on each other in a loop
+var ErrDependencyCycle = errors.New("facade: dependency cycle")
+
+// ErrUnknownDependency is returned by Start when a subsystem depends on one that is not registered
+var ErrUnknownDependency = errors.New("facade: unknown dependency")
+
+// Starter is implemented by subsystems that need to be brought up before use
+type Starter interface {
+    Start(ctx context.Context) error
+}
+
+// Stopper is implemented by subsystems that need to be shut down
+type Stopper interface {
+    Stop(ctx context.Context) error
+}
+
+// HealthChecker is implemented by subsystems that can report their own health
+type HealthChecker interface {
+    Health() Status
+}
+
+// Status is the health of a subsystem or of the whole facade
+type Status int
+
+const (
+    // StatusDown means the subsystem cannot serve requests
+    StatusDown Status = iota
+    // StatusDegraded means the subsystem serves requests with reduced capability
+    StatusDegraded
+    // StatusReady means the subsystem is fully operational
+    StatusReady
+)
+
+// String returns the name of the status
+func (s Status) String() string {
+    switch s {
+    case StatusReady:
+        return "ready"
+    case StatusDegraded:
+        return "degraded"
+    default:
+        return "down"
+    }
+}
+
+// SubsystemHealth is the health of one subsystem
+type SubsystemHealth struct {
+    Name     string
+    Status   Status
+    Critical bool
+}
+
+// HealthReport aggregates the health of every subsystem
+type HealthReport struct {
+    Status     Status
+    Subsystems []SubsystemHealth
+}
+
+// Start brings the subsystems up in dependency order. If one fails to start,
+// the ones already started are stopped in reverse order and the start error is
+// returned together with any errors from the rollback. The rollback runs even
+// when ctx is cancelled or expired, with ctx's values but without its deadline.
+func (f *Facade) Start(ctx context.Context) error {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if f.running {
+        return ErrAlreadyStarted
+    }
+
+    order, err := startOrder(f.subsystems)
+    if err != nil {
+        return err
+    }
+    started := make([]SubsystemSpec, 0, len(order))
+    rollback := func() error {
+        return stopAll(context.WithoutCancel(ctx), started)
+    }
+    for _, spec := range order {
+        if err := ctx.Err(); err != nil {
+            return errors.Join(err, rollback())
+        }
+        if starter, ok := spec.Subsystem.(Starter); ok {
+            if err := starter.Start(ctx); err != nil {
+                startErr := fmt.Errorf("facade: starting %s: %w", spec.Name, err)
+                return errors.Join(startErr, rollback())
+            }
+        }
+        started = append(started, spec)
+    }
+    f.started = started
+    f.running = true
+    return nil
+}
+
+// Stop shuts the subsystems down in the reverse of the order they were started.
+// Every subsystem is asked to stop even if an earlier one fails.
+func (f *Facade) Stop(ctx context.Context) error {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if !f.running {
+        return ErrNotStarted
+    }
+    err := stopAll(ctx, f.started)
+    f.started = nil
+    f.running = false
+    return err
+}
+
+// Health reports the status of every subsystem, in registration order, and the
+// overall status: ready when all are ready, down when every subsystem or any
+// critical subsystem is down, and degraded otherwise. Subsystems that do not
+// implement HealthChecker are ready while the facade is started.
+func (f *Facade) Health() HealthReport {
+    f.mu.Lock()
+    running := f.running
+    f.mu.Unlock()
+
+    report := HealthReport{Status: StatusReady}
+    if len(f.subsystems) == 0 && !running {
+        report.Status = StatusDown
+    }
+    down := 0
+    for _, spec := range f.subsystems {
+        status := StatusDown
+        if running {
+            status = StatusReady
+            if checker, ok := spec.Subsystem.(HealthChecker); ok {
+                status = checker.Health()
+            }
+        }
+        report.Subsystems = append(report.Subsystems, SubsystemHealth{
+            Name:     spec.Name,
+            Status:   status,
+            Critical: spec.Critical,
+        })
+
+        switch {
+        case status == StatusDown && spec.Critical:
+            report.Status = StatusDown
+        case status != StatusReady && report.Status == StatusReady:
+            report.Status = StatusDegraded
+        }
+        if status == StatusDown {
+            down++
+        }
+    }
+    if down > 0 && down == len(f.subsystems) {
+        report.Status = StatusDown
+    }
+    return report
+}
+
+// stopAll stops the given subsystems in reverse order and joins their errors
+func stopAll(ctx context.Context, started []SubsystemSpec) error {
+    var errs []error
+    for i := len(started) - 1; i >= 0; i-- {
+        spec := started[i]
+        if stopper, ok := spec.Subsystem.(Stopper); ok {
+            if err := stopper.Stop(ctx); err != nil {
+                errs = append(errs, fmt.Errorf("facade: stopping %s: %w", spec.Name, err))
+            }
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// startOrder sorts subsystems so each comes after its dependencies. Subsystems
+// with no ordering constraint between them keep their registration order.
+func startOrder(subsystems []SubsystemSpec) ([]SubsystemSpec, error) {
+    index := make(map[string]int, len(subsystems))
+    for i, spec := range subsystems {
+        index[spec.Name] = i
+    }
+    for _, spec := range subsystems {
+        for _, dep := range spec.DependsOn {
+            if _, ok := index[dep]; !ok {
+                return nil, fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, spec.Name, dep)
+            }
+        }
+    }
+
+    const (
+        unvisited = iota
+        visiting
+        done
+    )
+    state := make([]int, len(subsystems))
+    order := make([]SubsystemSpec, 0, len(subsystems))
+    var path []string
+    var visit func(i int) error
+    visit = func(i int) error {
+        switch state[i] {
+        case done:
+            return nil
+        case visiting:
+            return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(append(path, subsystems[i].Name), " -> "))
+        }
+        state[i] = visiting
+        path = append(path, subsystems[i].Name)
+        for _, dep := range subsystems[i].DependsOn {
+            if err := visit(index[dep]); err != nil {
+                return err
+            }
+        }
+        path = path[:len(path)-1]
+        state[i] = done
+        order = append(order, subsystems[i])
+        return nil
+    }
+    for i := range subsystems {
+        if err := visit(i); err != nil {
+            return nil, err
+        }
+    }
+    return order, nil
+}
@@ -0,0 +1,198 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// eventLog records lifecycle calls across fake subsystems
+type eventLog struct {
+    mu     sync.Mutex
+    events []string
+}
+
+func (l *eventLog) add(event string) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.events = append(l.events, event)
+}
+
+func (l *eventLog) list() []string {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    return append([]string(nil), l.events...)
+}
+
+// managedSubsystem is a fake subsystem with a lifecycle and a settable health
+type managedSubsystem struct {
+    name     string
+    log      *eventLog
+    startErr error
+    stopErr  error
+    status   Status
+    // onStart runs after a successful start
+    onStart func()
+}
+
+func (s *managedSubsystem) Call(ctx context.Context) (string, error) {
+    return s.name, nil
+}
+
+func (s *managedSubsystem) Start(ctx context.Context) error {
+    if s.startErr != nil {
+        s.log.add("fail " + s.name)
+        return s.startErr
+    }
+    s.log.add("start " + s.name)
+    s.status = StatusReady
+    if s.onStart != nil {
+        s.onStart()
+    }
+    return nil
+}
+
+func (s *managedSubsystem) Stop(ctx context.Context) error {
+    if err := ctx.Err(); err != nil {
+        s.log.add("abandon " + s.name)
+        return err
+    }
+    s.log.add("stop " + s.name)
+    s.status = StatusDown
+    return s.stopErr
+}
+
+func (s *managedSubsystem) Health() Status {
+    return s.status
+}
+
+func TestStartStopDependencyOrder(t *testing.T) {
+    log := &eventLog{}
+    db := &managedSubsystem{name: "db", log: log}
+    cache := &managedSubsystem{name: "cache", log: log}
+    api := &managedSubsystem{name: "api", log: log}
+    facade := NewFacadeWithSubsystems(nil,
+        SubsystemSpec{Name: "api", Subsystem: api, DependsOn: []string{"db", "cache"}},
+        SubsystemSpec{Name: "cache", Subsystem: cache, DependsOn: []string{"db"}},
+        SubsystemSpec{Name: "db", Subsystem: db},
+    )
+
+    if err := facade.Start(context.Background()); err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+    if err := facade.Start(context.Background()); !errors.Is(err, ErrAlreadyStarted) {
+        t.Errorf("Expected ErrAlreadyStarted, got %v", err)
+    }
+    if err := facade.Stop(context.Background()); err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+
+    expected := []string{"start db", "start cache", "start api", "stop api", "stop cache", "stop db"}
+    if events := log.list(); !reflect.DeepEqual(events, expected) {
+        t.Errorf("Expected %v, got %v", expected, events)
+    }
+    if err := facade.Stop(context.Background()); !errors.Is(err, ErrNotStarted) {
+        t.Errorf("Expected ErrNotStarted, got %v", err)
+    }
+}
+
+func TestStartRollsBack(t *testing.T) {
+    log := &eventLog{}
+    errBoom := errors.New("boom")
+    errStuck := errors.New("stuck")
+    facade := NewFacadeWithSubsystems(nil,
+        SubsystemSpec{Name: "a", Subsystem: &managedSubsystem{name: "a", log: log}},
+        SubsystemSpec{Name: "b", Subsystem: &managedSubsystem{name: "b", log: log, stopErr: errStuck}},
+        SubsystemSpec{Name: "c", Subsystem: &managedSubsystem{name: "c", log: log, startErr: errBoom}},
+        SubsystemSpec{Name: "d", Subsystem: &managedSubsystem{name: "d", log: log}},
+    )
+
+    err := facade.Start(context.Background())
+    if !errors.Is(err, errBoom) || !errors.Is(err, errStuck) {
+        t.Errorf("Expected start and rollback errors, got %v", err)
+    }
+    expected := []string{"start a", "start b", "fail c", "stop b", "stop a"}
+    if events := log.list(); !reflect.DeepEqual(events, expected) {
+        t.Errorf("Expected %v, got %v", expected, events)
+    }
+    if facade.Health().Status != StatusDown {
+        t.Errorf("Expected facade to be down after a failed start")
+    }
+}
+
+func TestStartRollsBackAfterCancel(t *testing.T) {
+    log := &eventLog{}
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    facade := NewFacadeWithSubsystems(nil,
+        SubsystemSpec{Name: "a", Subsystem: &managedSubsystem{name: "a", log: log}},
+        SubsystemSpec{Name: "b", Subsystem: &managedSubsystem{name: "b", log: log, onStart: cancel}},
+        SubsystemSpec{Name: "c", Subsystem: &managedSubsystem{name: "c", log: log}},
+    )
+
+    if err := facade.Start(ctx); !errors.Is(err, context.Canceled) {
+        t.Errorf("Expected context.Canceled, got %v", err)
+    }
+    expected := []string{"start a", "start b", "stop b", "stop a"}
+    if events := log.list(); !reflect.DeepEqual(events, expected) {
+        t.Errorf("Expected the started subsystems to be stopped, got %v", events)
+    }
+}
+
+func TestStartDependencyErrors(t *testing.T) {
+    cycle := NewFacadeWithSubsystems(nil,
+        SubsystemSpec{Name: "a", Subsystem: NewSubsystemA("a"), DependsOn: []string{"b"}},
+        SubsystemSpec{Name: "b", Subsystem: NewSubsystemB("b"), DependsOn: []string{"a"}},
+    )
+    if err := cycle.Start(context.Background()); !errors.Is(err, ErrDependencyCycle) {
+        t.Errorf("Expected ErrDependencyCycle, got %v", err)
+    }
+
+    unknown := NewFacadeWithSubsystems(nil,
+        SubsystemSpec{Name: "a", Subsystem: NewSubsystemA("a"), DependsOn: []string{"missing"}},
+    )
+    if err := unknown.Start(context.Background()); !errors.Is(err, ErrUnknownDependency) {
+        t.Errorf("Expected ErrUnknownDependency, got %v", err)
+    }
+}
+
+func TestHealth(t *testing.T) {
+    log := &eventLog{}
+    critical := &managedSubsystem{name: "critical", log: log}
+    optional := &managedSubsystem{name: "optional", log: log}
+    facade := NewFacadeWithSubsystems(nil,
+        SubsystemSpec{Name: "critical", Subsystem: critical, Critical: true},
+        SubsystemSpec{Name: "optional", Subsystem: optional},
+        SubsystemSpec{Name: "plain", Subsystem: NewSubsystemC("plain")},
+    )
+
+    if report := facade.Health(); report.Status != StatusDown {
+        t.Errorf("Expected down before start, got %s", report.Status)
+    }
+    facade.Start(context.Background())
+    if report := facade.Health(); report.Status != StatusReady {
+        t.Errorf("Expected ready after start, got %s", report.Status)
+    }
+
+    optional.status = StatusDown
+    report := facade.Health()
+    if report.Status != StatusDegraded {
+        t.Errorf("Expected degraded with optional subsystem down, got %s", report.Status)
+    }
+    if report.Subsystems[1].Status != StatusDown || report.Subsystems[2].Status != StatusReady {
+        t.Errorf("Unexpected subsystem statuses %+v", report.Subsystems)
+    }
+
+    optional.status = StatusReady
+    critical.status = StatusDegraded
+    if report := facade.Health(); report.Status != StatusDegraded {
+        t.Errorf("Expected degraded with critical subsystem degraded, got %s", report.Status)
+    }
+
+    critical.status = StatusDown
+    if report := facade.Health(); report.Status != StatusDown {
+        t.Errorf("Expected down with critical subsystem down, got %s", report.Status)
+    }
+}
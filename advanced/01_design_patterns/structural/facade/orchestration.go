@@ -0,0 +1,214 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrPolicyNotMet is returned by Execute when too many subsystems failed
+var ErrPolicyNotMet = errors.New("facade: success policy not met")
+
+// Subsystem is one part of the system the facade coordinates
+type Subsystem interface {
+    Call(ctx context.Context) (string, error)
+}
+
+// SubsystemFunc lets an ordinary function act as a Subsystem
+type SubsystemFunc func(ctx context.Context) (string, error)
+
+// Call implements the Subsystem interface
+func (f SubsystemFunc) Call(ctx context.Context) (string, error) {
+    return f(ctx)
+}
+
+// SubsystemSpec registers a subsystem with the facade
+type SubsystemSpec struct {
+    Name      string
+    Subsystem Subsystem
+    // Timeout bounds each call to the subsystem. Zero means only ctx applies.
+    Timeout time.Duration
+    // DependsOn names the subsystems that must be started before this one
+    DependsOn []string
+    // Critical marks a subsystem whose failure takes the whole facade down
+    Critical bool
+}
+
+// SubsystemResult is the outcome of calling one subsystem
+type SubsystemResult struct {
+    Name    string
+    Output  string
+    Latency time.Duration
+    Err     error
+}
+
+// Result collects the outcome of every subsystem, in registration order
+type Result struct {
+    Subsystems []SubsystemResult
+}
+
+// Get returns the result for the named subsystem
+func (r *Result) Get(name string) (SubsystemResult, bool) {
+    for _, s := range r.Subsystems {
+        if s.Name == name {
+            return s, true
+        }
+    }
+    return SubsystemResult{}, false
+}
+
+// Succeeded returns the number of subsystems that returned without error
+func (r *Result) Succeeded() int {
+    n := 0
+    for _, s := range r.Subsystems {
+        if s.Err == nil {
+            n++
+        }
+    }
+    return n
+}
+
+// Partial reports whether some, but not all, subsystems failed
+func (r *Result) Partial() bool {
+    n := r.Succeeded()
+    return n > 0 && n < len(r.Subsystems)
+}
+
+// Policy decides whether a set of subsystem results counts as success
+type Policy interface {
+    Evaluate(results []SubsystemResult) error
+}
+
+// PolicyFunc lets an ordinary function act as a Policy
+type PolicyFunc func(results []SubsystemResult) error
+
+// Evaluate implements the Policy interface
+func (f PolicyFunc) Evaluate(results []SubsystemResult) error {
+    return f(results)
+}
+
+// RequireAll succeeds only if every subsystem succeeded
+func RequireAll() Policy {
+    return RequireQuorum(-1)
+}
+
+// RequireAny succeeds if at least one subsystem succeeded
+func RequireAny() Policy {
+    return RequireQuorum(1)
+}
+
+// RequireQuorum succeeds if at least n subsystems succeeded.
+// A negative n means every subsystem.
+func RequireQuorum(n int) Policy {
+    return PolicyFunc(func(results []SubsystemResult) error {
+        required := n
+        if required < 0 {
+            required = len(results)
+        }
+        succeeded := 0
+        for _, r := range results {
+            if r.Err == nil {
+                succeeded++
+            }
+        }
+        if succeeded < required {
+            return fmt.Errorf("%w: %d of %d subsystems succeeded, %d required",
+                ErrPolicyNotMet, succeeded, len(results), required)
+        }
+        return nil
+    })
+}
+
+// RequireSubsystems succeeds if the named critical subsystems succeeded,
+// whatever happened to the others
+func RequireSubsystems(names ...string) Policy {
+    return PolicyFunc(func(results []SubsystemResult) error {
+        for _, name := range names {
+            found := false
+            for _, r := range results {
+                if r.Name != name {
+                    continue
+                }
+                found = true
+                if r.Err != nil {
+                    return fmt.Errorf("%w: critical subsystem %s failed", ErrPolicyNotMet, name)
+                }
+            }
+            if !found {
+                return fmt.Errorf("%w: critical subsystem %s is not registered", ErrPolicyNotMet, name)
+            }
+        }
+        return nil
+    })
+}
+
+// NewFacadeWithSubsystems creates a Facade over the given subsystems.
+// A nil policy means RequireAll.
+func NewFacadeWithSubsystems(policy Policy, subsystems ...SubsystemSpec) *Facade {
+    if policy == nil {
+        policy = RequireAll()
+    }
+    return &Facade{subsystems: subsystems, policy: policy}
+}
+
+// Execute calls every subsystem concurrently, each bounded by its own timeout,
+// and waits for all of them. The result always holds every subsystem's output,
+// latency and error. The returned error is non-nil when the facade's policy
+// rejects the results; it wraps ErrPolicyNotMet and each subsystem error.
+func (f *Facade) Execute(ctx context.Context) (*Result, error) {
+    results := make([]SubsystemResult, len(f.subsystems))
+    var wg sync.WaitGroup
+    for i, spec := range f.subsystems {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            results[i] = call(ctx, spec)
+        }()
+    }
+    wg.Wait()
+
+    result := &Result{Subsystems: results}
+    if err := f.policy.Evaluate(results); err != nil {
+        errs := []error{err}
+        for _, r := range results {
+            if r.Err != nil {
+                errs = append(errs, fmt.Errorf("%s: %w", r.Name, r.Err))
+            }
+        }
+        return result, errors.Join(errs...)
+    }
+    return result, nil
+}
+
+// call runs one subsystem, giving up once its timeout passes even if the
+// subsystem ignores its context
+func call(ctx context.Context, spec SubsystemSpec) SubsystemResult {
+    if spec.Timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+        defer cancel()
+    }
+
+    type outcome struct {
+        output string
+        err    error
+    }
+    done := make(chan outcome, 1)
+    start := time.Now()
+    go func() {
+        output, err := spec.Subsystem.Call(ctx)
+        done <- outcome{output: output, err: err}
+    }()
+
+    result := SubsystemResult{Name: spec.Name}
+    select {
+    case o := <-done:
+        result.Output, result.Err = o.output, o.err
+    case <-ctx.Done():
+        result.Err = ctx.Err()
+    }
+    result.Latency = time.Since(start)
+    return result
+}
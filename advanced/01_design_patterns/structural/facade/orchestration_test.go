@@ -0,0 +1,142 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowSubsystem answers after a delay, ignoring its context
+func slowSubsystem(delay time.Duration, output string) Subsystem {
+    return SubsystemFunc(func(ctx context.Context) (string, error) {
+        time.Sleep(delay)
+        return output, nil
+    })
+}
+
+// failingSubsystem always returns err
+func failingSubsystem(err error) Subsystem {
+    return SubsystemFunc(func(ctx context.Context) (string, error) {
+        return "", err
+    })
+}
+
+func TestExecuteRunsConcurrently(t *testing.T) {
+    facade := NewFacadeWithSubsystems(RequireAll(),
+        SubsystemSpec{Name: "one", Subsystem: slowSubsystem(50*time.Millisecond, "1")},
+        SubsystemSpec{Name: "two", Subsystem: slowSubsystem(50*time.Millisecond, "2")},
+        SubsystemSpec{Name: "three", Subsystem: slowSubsystem(50*time.Millisecond, "3")},
+    )
+
+    start := time.Now()
+    result, err := facade.Execute(context.Background())
+    if err != nil {
+        t.Fatalf("Unexpected error: %v", err)
+    }
+    if elapsed := time.Since(start); elapsed >= 140*time.Millisecond {
+        t.Errorf("Expected subsystems to run concurrently, took %v", elapsed)
+    }
+    for i, name := range []string{"one", "two", "three"} {
+        r := result.Subsystems[i]
+        if r.Name != name || r.Err != nil || r.Latency < 50*time.Millisecond {
+            t.Errorf("Unexpected result for %s: %+v", name, r)
+        }
+    }
+}
+
+func TestExecuteTimeout(t *testing.T) {
+    facade := NewFacadeWithSubsystems(RequireAny(),
+        SubsystemSpec{Name: "fast", Subsystem: slowSubsystem(0, "fast")},
+        SubsystemSpec{Name: "slow", Subsystem: slowSubsystem(time.Second, "slow"), Timeout: 20 * time.Millisecond},
+    )
+
+    start := time.Now()
+    result, err := facade.Execute(context.Background())
+    if err != nil {
+        t.Fatalf("Expected partial success to pass RequireAny, got %v", err)
+    }
+    if time.Since(start) >= time.Second {
+        t.Error("Expected the slow subsystem to be abandoned at its timeout")
+    }
+    slow, _ := result.Get("slow")
+    if !errors.Is(slow.Err, context.DeadlineExceeded) {
+        t.Errorf("Expected DeadlineExceeded for slow subsystem, got %v", slow.Err)
+    }
+    if !result.Partial() || result.Succeeded() != 1 {
+        t.Errorf("Expected a partial result with 1 success, got %d", result.Succeeded())
+    }
+}
+
+func TestExecutePolicies(t *testing.T) {
+    errDown := errors.New("down")
+    specs := []SubsystemSpec{
+        {Name: "A", Subsystem: slowSubsystem(0, "a")},
+        {Name: "B", Subsystem: failingSubsystem(errDown)},
+        {Name: "C", Subsystem: slowSubsystem(0, "c")},
+    }
+
+    tests := []struct {
+        name    string
+        policy  Policy
+        success bool
+    }{
+        {"all", RequireAll(), false},
+        {"any", RequireAny(), true},
+        {"quorum of 2", RequireQuorum(2), true},
+        {"quorum of 3", RequireQuorum(3), false},
+        {"critical A and C", RequireSubsystems("A", "C"), true},
+        {"critical B", RequireSubsystems("B"), false},
+        {"critical missing", RequireSubsystems("D"), false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            result, err := NewFacadeWithSubsystems(tt.policy, specs...).Execute(context.Background())
+            if tt.success && err != nil {
+                t.Errorf("Expected success, got %v", err)
+            }
+            if !tt.success {
+                if !errors.Is(err, ErrPolicyNotMet) {
+                    t.Errorf("Expected ErrPolicyNotMet, got %v", err)
+                }
+                if tt.name != "critical missing" && !errors.Is(err, errDown) {
+                    t.Errorf("Expected error to wrap the subsystem error, got %v", err)
+                }
+            }
+            if len(result.Subsystems) != 3 {
+                t.Errorf("Expected 3 results, got %d", len(result.Subsystems))
+            }
+        })
+    }
+}
+
+func TestExecuteCancelled(t *testing.T) {
+    facade := NewFacadeWithSubsystems(RequireAll(),
+        SubsystemSpec{Name: "slow", Subsystem: slowSubsystem(time.Second, "slow")},
+    )
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    result, err := facade.Execute(ctx)
+    if !errors.Is(err, context.Canceled) {
+        t.Errorf("Expected context.Canceled, got %v", err)
+    }
+    if r, _ := result.Get("slow"); !errors.Is(r.Err, context.Canceled) {
+        t.Errorf("Expected slow subsystem to report cancellation, got %v", r.Err)
+    }
+}
+
+func TestOperationReportsFailures(t *testing.T) {
+    facade := NewFacadeWithSubsystems(RequireAny(),
+        SubsystemSpec{Name: "A", Subsystem: NewSubsystemA("test_A")},
+        SubsystemSpec{Name: "B", Subsystem: failingSubsystem(errors.New("down"))},
+    )
+    expected := "Facade operation:\n- SubsystemA test_A operation\n- B failed: down"
+    if result := facade.Operation(); result != expected {
+        t.Errorf("Expected '%s', got '%s'", expected, result)
+    }
+    if !strings.HasPrefix(NewFacade("test").Operation(), "Facade operation:") {
+        t.Error("Expected NewFacade to keep its output format")
+    }
+}
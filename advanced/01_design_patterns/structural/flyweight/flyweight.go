@@ -1,42 +1,20 @@
 package flyweight
 
-import (
-	"sync"
-)
-
 // FlyweightFactory creates and manages flyweight objects
 type FlyweightFactory struct {
-    flyweights map[string]*ConcreteFlyweight
-    mutex      sync.RWMutex
+    *KeyedFactory[string, *ConcreteFlyweight]
 }
 
 // NewFlyweightFactory creates a new FlyweightFactory
-func NewFlyweightFactory() *FlyweightFactory {
+func NewFlyweightFactory(opts ...FactoryOption) *FlyweightFactory {
     return &FlyweightFactory{
-        flyweights: make(map[string]*ConcreteFlyweight),
+        KeyedFactory: NewKeyedFactory(NewConcreteFlyweight, opts...),
     }
 }
 
 // GetFlyweight returns a flyweight object based on the key
 func (f *FlyweightFactory) GetFlyweight(key string) *ConcreteFlyweight {
-    f.mutex.RLock()
-    if flyweight, exists := f.flyweights[key]; exists {
-        f.mutex.RUnlock()
-        return flyweight
-    }
-    f.mutex.RUnlock()
-
-    f.mutex.Lock()
-    defer f.mutex.Unlock()
-
-    // Double-check after acquiring write lock
-    if flyweight, exists := f.flyweights[key]; exists {
-        return flyweight
-    }
-
-    flyweight := NewConcreteFlyweight(key)
-    f.flyweights[key] = flyweight
-    return flyweight
+    return f.Get(key)
 }
 
 // ConcreteFlyweight represents the shared flyweight object
@@ -56,6 +34,11 @@ func (f *ConcreteFlyweight) Operation(extrinsicState string) string {
     return "Intrinsic: " + f.intrinsicState + ", Extrinsic: " + extrinsicState
 }
 
+// OperationBatch performs Operation for each extrinsic state in one call
+func (f *ConcreteFlyweight) OperationBatch(extrinsicStates []string) []string {
+    return OperationBatch(f, extrinsicStates)
+}
+
 // UnsharedConcreteFlyweight represents unshared flyweight objects
 type UnsharedConcreteFlyweight struct {
     allState string
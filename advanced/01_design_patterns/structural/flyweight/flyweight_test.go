@@ -1,6 +1,8 @@
 package flyweight
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 )
 
@@ -58,4 +60,128 @@ func TestClient(t *testing.T) {
     if result := client.UseUnsharedFlyweight("all"); result != expected {
         t.Errorf("Expected '%s', got '%s'", expected, result)
     }
-} 
\ No newline at end of file
+}
+
+func TestUnboundedFactoryStats(t *testing.T) {
+    factory := NewFlyweightFactory()
+    factory.GetFlyweight("a")
+    factory.GetFlyweight("a")
+    factory.GetFlyweight("b")
+
+    stats := factory.Stats()
+    if stats.Hits != 1 || stats.Misses != 2 || stats.Evictions != 0 || stats.Live != 2 {
+        t.Errorf("Unexpected stats %+v", stats)
+    }
+}
+
+func TestFactoryEvictsLeastRecentlyUsed(t *testing.T) {
+    factory := NewFlyweightFactory(WithCapacity(3))
+    a := factory.GetFlyweight("a")
+    factory.GetFlyweight("b")
+    factory.GetFlyweight("c")
+
+    // Using "a" again protects it from the next eviction
+    factory.GetFlyweight("a")
+    factory.GetFlyweight("d")
+
+    stats := factory.Stats()
+    if stats.Live != 3 || stats.Evictions != 1 {
+        t.Fatalf("Expected 3 live flyweights and 1 eviction, got %+v", stats)
+    }
+    if factory.GetFlyweight("a") != a {
+        t.Error("Expected recently used flyweight 'a' to survive")
+    }
+    misses := factory.Stats().Misses
+    factory.GetFlyweight("b")
+    if factory.Stats().Misses != misses+1 {
+        t.Error("Expected least recently used flyweight 'b' to be evicted")
+    }
+}
+
+func TestFactoryNeverEvictsAcquired(t *testing.T) {
+    factory := NewFlyweightFactory(WithCapacity(2))
+    pinned, release := factory.Acquire("pinned")
+
+    for i := 0; i < 10; i++ {
+        factory.GetFlyweight(fmt.Sprintf("key%d", i))
+    }
+    if factory.GetFlyweight("pinned") != pinned {
+        t.Fatal("Expected acquired flyweight to survive eviction")
+    }
+
+    release()
+    release()
+    for i := 10; i < 20; i++ {
+        factory.GetFlyweight(fmt.Sprintf("key%d", i))
+    }
+    if factory.GetFlyweight("pinned") == pinned {
+        t.Error("Expected released flyweight to become evictable")
+    }
+}
+
+func TestFactoryOverCapacityWhenAllPinned(t *testing.T) {
+    factory := NewFlyweightFactory(WithCapacity(2))
+    _, releaseA := factory.Acquire("a")
+    _, releaseB := factory.Acquire("b")
+    factory.GetFlyweight("c")
+
+    if live := factory.Stats().Live; live != 3 {
+        t.Fatalf("Expected the factory to go over capacity, got %d live", live)
+    }
+    releaseA()
+    releaseB()
+    factory.GetFlyweight("d")
+    if live := factory.Stats().Live; live > 3 {
+        t.Errorf("Expected eviction to resume after release, got %d live", live)
+    }
+}
+
+func TestFactoryConcurrentAccess(t *testing.T) {
+    factory := NewFlyweightFactory(WithCapacity(16))
+    var wg sync.WaitGroup
+    for g := 0; g < 8; g++ {
+        wg.Add(1)
+        go func(g int) {
+            defer wg.Done()
+            for i := 0; i < 1000; i++ {
+                key := fmt.Sprintf("key%d", (i*g)%32)
+                flyweight, release := factory.Acquire(key)
+                if result := flyweight.Operation("x"); result != "Intrinsic: "+key+", Extrinsic: x" {
+                    t.Errorf("Unexpected result '%s'", result)
+                }
+                release()
+            }
+        }(g)
+    }
+    wg.Wait()
+
+    stats := factory.Stats()
+    if stats.Hits+stats.Misses != 8000 {
+        t.Errorf("Expected 8000 lookups, got %d", stats.Hits+stats.Misses)
+    }
+    if stats.Live > 16 {
+        t.Errorf("Expected at most 16 live flyweights, got %d", stats.Live)
+    }
+}
+
+func BenchmarkGetFlyweightHit(b *testing.B) {
+    factory := NewFlyweightFactory(WithCapacity(1024))
+    factory.GetFlyweight("shared")
+    b.RunParallel(func(pb *testing.PB) {
+        for pb.Next() {
+            factory.GetFlyweight("shared")
+        }
+    })
+}
+
+func BenchmarkGetFlyweightEvicting(b *testing.B) {
+    factory := NewFlyweightFactory(WithCapacity(1024))
+    keys := make([]string, 4096)
+    for i := range keys {
+        keys[i] = fmt.Sprintf("key%d", i)
+    }
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        factory.GetFlyweight(keys[i%len(keys)])
+    }
+}
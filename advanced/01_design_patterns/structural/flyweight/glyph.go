@@ -0,0 +1,130 @@
+package flyweight
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// GlyphKey holds every intrinsic field of a glyph
+type GlyphKey struct {
+    Char rune
+    Font string
+    Size int
+    Bold bool
+}
+
+// Position is the extrinsic state of a glyph: where it is drawn
+type Position struct {
+    X, Y int
+}
+
+// Glyph is a flyweight holding a rendered character shape
+type Glyph struct {
+    key    GlyphKey
+    bitmap []byte
+}
+
+// NewGlyph renders the bitmap for a glyph. The bitmap is Size x Size bytes, standing
+// in for the real outline data a font renderer would produce. A negative Size
+// is treated as 0.
+func NewGlyph(key GlyphKey) *Glyph {
+    key.Size = max(key.Size, 0)
+    bitmap := make([]byte, key.Size*key.Size)
+    for i := range bitmap {
+        bitmap[i] = byte(int(key.Char) + i)
+    }
+    return &Glyph{key: key, bitmap: bitmap}
+}
+
+// Key returns the glyph's intrinsic state
+func (g *Glyph) Key() GlyphKey {
+    return g.key
+}
+
+// Operation draws the glyph at a position
+func (g *Glyph) Operation(position Position) string {
+    return fmt.Sprintf("%c@(%d,%d)", g.key.Char, position.X, position.Y)
+}
+
+// Bytes returns the number of bytes the glyph occupies
+func (g *Glyph) Bytes() int {
+    return int(unsafe.Sizeof(*g)) + len(g.key.Font) + len(g.bitmap)
+}
+
+// GlyphFactory shares glyphs between every page that uses the same font settings
+type GlyphFactory = KeyedFactory[GlyphKey, *Glyph]
+
+// NewGlyphFactory creates a new GlyphFactory
+func NewGlyphFactory(opts ...FactoryOption) *GlyphFactory {
+    return NewKeyedFactory(NewGlyph, opts...)
+}
+
+// placedGlyph pairs a shared glyph with its extrinsic position
+type placedGlyph struct {
+    glyph    *Glyph
+    position Position
+}
+
+// Page is a block of text laid out with shared glyphs
+type Page struct {
+    glyphs []placedGlyph
+}
+
+// NewPage lays text out in lines of the given width using glyphs from factory.
+// A negative size is treated as 0.
+func NewPage(factory *GlyphFactory, text, font string, size int, bold bool, width int) *Page {
+    size = max(size, 0)
+    page := &Page{}
+    x, y := 0, 0
+    for _, char := range text {
+        if char == '\n' || x >= width {
+            x, y = 0, y+1
+            if char == '\n' {
+                continue
+            }
+        }
+        glyph := factory.Get(GlyphKey{Char: char, Font: font, Size: size, Bold: bold})
+        page.glyphs = append(page.glyphs, placedGlyph{glyph: glyph, position: Position{X: x * size, Y: y * size}})
+        x++
+    }
+    return page
+}
+
+// Render draws every glyph on the page in layout order. Positions are grouped
+// by glyph so each glyph is drawn with a single OperationBatch call.
+func (p *Page) Render() []string {
+    var order []*Glyph
+    positions := make(map[*Glyph][]Position)
+    indexes := make(map[*Glyph][]int)
+    for i, placed := range p.glyphs {
+        if _, seen := positions[placed.glyph]; !seen {
+            order = append(order, placed.glyph)
+        }
+        positions[placed.glyph] = append(positions[placed.glyph], placed.position)
+        indexes[placed.glyph] = append(indexes[placed.glyph], i)
+    }
+
+    output := make([]string, len(p.glyphs))
+    for _, glyph := range order {
+        for j, drawn := range OperationBatch(glyph, positions[glyph]) {
+            output[indexes[glyph][j]] = drawn
+        }
+    }
+    return output
+}
+
+// MemoryUsage reports the bytes the page uses with shared glyphs and the bytes
+// it would use if every character carried its own copy of the glyph
+func (p *Page) MemoryUsage() (shared, unshared int) {
+    counted := make(map[*Glyph]bool)
+    placedSize := int(unsafe.Sizeof(placedGlyph{}))
+    for _, placed := range p.glyphs {
+        shared += placedSize
+        unshared += int(unsafe.Sizeof(Position{})) + placed.glyph.Bytes()
+        if !counted[placed.glyph] {
+            counted[placed.glyph] = true
+            shared += placed.glyph.Bytes()
+        }
+    }
+    return shared, unshared
+}
@@ -0,0 +1,75 @@
+package flyweight
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPageSharesGlyphs(t *testing.T) {
+    factory := NewGlyphFactory()
+    page := NewPage(factory, "abba\ncab", "serif", 10, false, 80)
+
+    expected := []string{
+        "a@(0,0)", "b@(10,0)", "b@(20,0)", "a@(30,0)",
+        "c@(0,10)", "a@(10,10)", "b@(20,10)",
+    }
+    if result := page.Render(); !reflect.DeepEqual(result, expected) {
+        t.Errorf("Expected %v, got %v", expected, result)
+    }
+    if live := factory.Stats().Live; live != 3 {
+        t.Errorf("Expected 3 shared glyphs, got %d", live)
+    }
+}
+
+func TestPageSeparatesStyles(t *testing.T) {
+    factory := NewGlyphFactory()
+    NewPage(factory, "aa", "serif", 10, false, 80)
+    NewPage(factory, "aa", "serif", 10, true, 80)
+    NewPage(factory, "aa", "mono", 10, false, 80)
+
+    if live := factory.Stats().Live; live != 3 {
+        t.Errorf("Expected one glyph per style, got %d", live)
+    }
+}
+
+func TestPageWraps(t *testing.T) {
+    page := NewPage(NewGlyphFactory(), "abc", "serif", 1, false, 2)
+    expected := []string{"a@(0,0)", "b@(1,0)", "c@(0,1)"}
+    if result := page.Render(); !reflect.DeepEqual(result, expected) {
+        t.Errorf("Expected %v, got %v", expected, result)
+    }
+}
+
+func TestNegativeGlyphSize(t *testing.T) {
+    glyph := NewGlyph(GlyphKey{Char: 'a', Size: -3})
+    if glyph.Key().Size != 0 || len(glyph.bitmap) != 0 {
+        t.Errorf("Expected a negative size to be treated as 0, got %+v", glyph.Key())
+    }
+
+    page := NewPage(NewGlyphFactory(), "ab", "serif", -1, false, 80)
+    if rendered := page.Render(); !reflect.DeepEqual(rendered, []string{"a@(0,0)", "b@(0,0)"}) {
+        t.Errorf("Unexpected render %v", rendered)
+    }
+}
+
+func TestPageMemorySavings(t *testing.T) {
+    text := strings.Repeat("the quick brown fox jumps over the lazy dog ", 250)
+    page := NewPage(NewGlyphFactory(), text, "serif", 16, false, 80)
+
+    shared, unshared := page.MemoryUsage()
+    t.Logf("%d characters: %d bytes shared, %d bytes unshared", len(text), shared, unshared)
+    if shared*10 > unshared {
+        t.Errorf("Expected sharing glyphs to save at least 90%%, got %d vs %d bytes", shared, unshared)
+    }
+}
+
+func BenchmarkPageRender(b *testing.B) {
+    text := strings.Repeat("the quick brown fox jumps over the lazy dog ", 250)
+    page := NewPage(NewGlyphFactory(), text, "serif", 16, false, 80)
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        page.Render()
+    }
+}
@@ -0,0 +1,197 @@
+package flyweight
+
+import (
+	"hash/maphash"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Handle is a compact reference to an interned value. The zero Handle is never
+// returned by Intern, so it can be used as "no value".
+//
+// A handle packs the shard (8 bits), a generation (24 bits) and a slot index
+// (32 bits). The generation changes when a slot is reused, so a handle kept
+// after its value was released no longer resolves.
+type Handle uint64
+
+const (
+    maxShards  = 1 << 8
+    shardShift = 56
+    genShift   = 32
+    genMask    = 1<<24 - 1
+    indexMask  = 1<<32 - 1
+)
+
+func newHandle(shard int, gen, index uint32) Handle {
+    return Handle(uint64(shard)<<shardShift | uint64(gen&genMask)<<genShift | uint64(index))
+}
+
+func (h Handle) shard() int    { return int(h >> shardShift) }
+func (h Handle) gen() uint32   { return uint32(h>>genShift) & genMask }
+func (h Handle) index() uint32 { return uint32(h & indexMask) }
+
+// Interner is a flyweight factory for plain values such as strings, label sets
+// or small structs. Equal values share one copy, identified by a Handle.
+// Every Intern call takes a reference that Release gives back; a value whose
+// last reference is released is dropped so the garbage collector can reclaim it.
+// An Interner is safe for concurrent use.
+//
+// It is a separate structure rather than a wrapper around KeyedFactory: the
+// factory evicts by capacity under one lock, while interning needs per-shard
+// locks, release at zero references and handle-to-value lookup.
+type Interner[T comparable] struct {
+    seed   maphash.Seed
+    shards []*internShard[T]
+}
+
+type internShard[T comparable] struct {
+    mu    sync.RWMutex
+    ids   map[T]uint32
+    slots []*internSlot[T]
+    free  []uint32
+}
+
+type internSlot[T comparable] struct {
+    value T
+    gen   uint32
+    refs  atomic.Int64
+}
+
+// NewInterner creates an Interner with the given number of shards, at most 256.
+// Zero or less picks a number based on GOMAXPROCS.
+func NewInterner[T comparable](shards int) *Interner[T] {
+    if shards <= 0 {
+        shards = 4 * runtime.GOMAXPROCS(0)
+    }
+    if shards > maxShards {
+        shards = maxShards
+    }
+    in := &Interner[T]{seed: maphash.MakeSeed(), shards: make([]*internShard[T], shards)}
+    for i := range in.shards {
+        in.shards[i] = &internShard[T]{ids: make(map[T]uint32)}
+    }
+    return in
+}
+
+// Intern returns the handle for value, storing value if it is not interned yet
+func (in *Interner[T]) Intern(value T) Handle {
+    shardIndex := int(maphash.Comparable(in.seed, value) % uint64(len(in.shards)))
+    shard := in.shards[shardIndex]
+
+    shard.mu.RLock()
+    if index, ok := shard.ids[value]; ok {
+        slot := shard.slots[index]
+        // Taking a reference under the read lock is safe because slots are only freed under the write lock
+        slot.refs.Add(1)
+        h := newHandle(shardIndex, slot.gen, index)
+        shard.mu.RUnlock()
+        return h
+    }
+    shard.mu.RUnlock()
+
+    shard.mu.Lock()
+    defer shard.mu.Unlock()
+    if index, ok := shard.ids[value]; ok {
+        slot := shard.slots[index]
+        slot.refs.Add(1)
+        return newHandle(shardIndex, slot.gen, index)
+    }
+
+    var index uint32
+    if n := len(shard.free); n > 0 {
+        index = shard.free[n-1]
+        shard.free = shard.free[:n-1]
+    } else {
+        index = uint32(len(shard.slots))
+        shard.slots = append(shard.slots, &internSlot[T]{gen: 1})
+    }
+    slot := shard.slots[index]
+    slot.value = value
+    slot.refs.Store(1)
+    shard.ids[value] = index
+    return newHandle(shardIndex, slot.gen, index)
+}
+
+// Value returns the value for a handle. It reports false for the zero handle
+// and for handles whose value has been released.
+func (in *Interner[T]) Value(h Handle) (T, bool) {
+    var zero T
+    slot, shard := in.lookup(h)
+    if slot == nil {
+        return zero, false
+    }
+    defer shard.mu.RUnlock()
+    if slot.gen != h.gen() || slot.refs.Load() <= 0 {
+        return zero, false
+    }
+    return slot.value, true
+}
+
+// Release gives back one reference taken by Intern. When the last reference is
+// released the value is dropped and its slot reused. Release reports false if
+// the handle was already invalid.
+func (in *Interner[T]) Release(h Handle) bool {
+    slot, shard := in.lookup(h)
+    if slot == nil {
+        return false
+    }
+    if slot.gen != h.gen() {
+        shard.mu.RUnlock()
+        return false
+    }
+    var remaining int64
+    for {
+        refs := slot.refs.Load()
+        if refs <= 0 {
+            shard.mu.RUnlock()
+            return false
+        }
+        if slot.refs.CompareAndSwap(refs, refs-1) {
+            remaining = refs - 1
+            break
+        }
+    }
+    shard.mu.RUnlock()
+    if remaining > 0 {
+        return true
+    }
+
+    shard.mu.Lock()
+    defer shard.mu.Unlock()
+    // Another Intern may have taken a new reference in the meantime
+    if slot.gen == h.gen() && slot.refs.Load() == 0 {
+        var zero T
+        delete(shard.ids, slot.value)
+        slot.value = zero
+        slot.gen = slot.gen%genMask + 1
+        shard.free = append(shard.free, h.index())
+    }
+    return true
+}
+
+// Len returns the number of distinct values currently interned
+func (in *Interner[T]) Len() int {
+    n := 0
+    for _, shard := range in.shards {
+        shard.mu.RLock()
+        n += len(shard.ids)
+        shard.mu.RUnlock()
+    }
+    return n
+}
+
+// lookup finds the slot for h and returns it with its shard read-locked.
+// It returns nil, with no lock held, if h cannot refer to any slot.
+func (in *Interner[T]) lookup(h Handle) (*internSlot[T], *internShard[T]) {
+    if h == 0 || h.shard() >= len(in.shards) {
+        return nil, nil
+    }
+    shard := in.shards[h.shard()]
+    shard.mu.RLock()
+    if int(h.index()) >= len(shard.slots) {
+        shard.mu.RUnlock()
+        return nil, nil
+    }
+    return shard.slots[h.index()], shard
+}
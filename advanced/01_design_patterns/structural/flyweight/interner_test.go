@@ -0,0 +1,180 @@
+package flyweight
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestInternerSharesValues(t *testing.T) {
+    interner := NewInterner[string](4)
+    a := interner.Intern("service=api")
+    b := interner.Intern(strings.Join([]string{"service", "api"}, "="))
+    c := interner.Intern("service=db")
+
+    if a != b {
+        t.Errorf("Expected equal values to share a handle, got %d and %d", a, b)
+    }
+    if a == c || a == 0 {
+        t.Errorf("Expected distinct non-zero handles, got %d and %d", a, c)
+    }
+    if value, ok := interner.Value(c); !ok || value != "service=db" {
+        t.Errorf("Expected 'service=db', got '%s', %v", value, ok)
+    }
+    if interner.Len() != 2 {
+        t.Errorf("Expected 2 interned values, got %d", interner.Len())
+    }
+    if _, ok := interner.Value(0); ok {
+        t.Error("Expected the zero handle to resolve to nothing")
+    }
+}
+
+func TestInternerRelease(t *testing.T) {
+    interner := NewInterner[string](1)
+    first := interner.Intern("tag")
+    interner.Intern("tag")
+
+    if !interner.Release(first) {
+        t.Fatal("Expected first release to succeed")
+    }
+    if _, ok := interner.Value(first); !ok {
+        t.Fatal("Expected value to survive while referenced")
+    }
+    interner.Release(first)
+    if _, ok := interner.Value(first); ok {
+        t.Fatal("Expected value to be dropped after the last release")
+    }
+    if interner.Release(first) {
+        t.Error("Expected releasing a dropped handle to fail")
+    }
+
+    // The slot is reused, but the stale handle must not see the new value
+    second := interner.Intern("other")
+    if second == first {
+        t.Error("Expected a reused slot to get a new generation")
+    }
+    if _, ok := interner.Value(first); ok {
+        t.Error("Expected the stale handle to stay invalid")
+    }
+    if interner.Len() != 1 {
+        t.Errorf("Expected 1 interned value, got %d", interner.Len())
+    }
+}
+
+type labelSet struct {
+    service, env, region string
+}
+
+func TestInternerStructValues(t *testing.T) {
+    interner := NewInterner[labelSet](0)
+    h := interner.Intern(labelSet{"api", "prod", "eu"})
+    if interner.Intern(labelSet{"api", "prod", "eu"}) != h {
+        t.Error("Expected equal structs to share a handle")
+    }
+    if value, _ := interner.Value(h); value.region != "eu" {
+        t.Errorf("Unexpected value %+v", value)
+    }
+}
+
+func TestInternerConcurrent(t *testing.T) {
+    interner := NewInterner[string](8)
+    var wg sync.WaitGroup
+    for g := 0; g < 8; g++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := 0; i < 2000; i++ {
+                value := fmt.Sprintf("v%d", i%50)
+                h := interner.Intern(value)
+                if got, ok := interner.Value(h); !ok || got != value {
+                    t.Errorf("Expected '%s', got '%s', %v", value, got, ok)
+                    return
+                }
+                interner.Release(h)
+            }
+        }()
+    }
+    wg.Wait()
+    if interner.Len() != 0 {
+        t.Errorf("Expected every value to be released, got %d", interner.Len())
+    }
+}
+
+// Benchmarks: 1M label values drawn from 1000 distinct strings, each built at
+// run time as it would be when parsed from input. "retained-B/value" is the
+// heap still in use per value after the run.
+
+const (
+    benchValues   = 1 << 20
+    benchDistinct = 1000
+)
+
+func benchInput() []string {
+    input := make([]string, benchValues)
+    for i := range input {
+        input[i] = fmt.Sprintf("label-value-%d", i%benchDistinct)
+    }
+    return input
+}
+
+func heapInUse() uint64 {
+    runtime.GC()
+    var stats runtime.MemStats
+    runtime.ReadMemStats(&stats)
+    return stats.HeapAlloc
+}
+
+func BenchmarkInternerStrings(b *testing.B) {
+    input := benchInput()
+    b.ReportAllocs()
+    b.ResetTimer()
+    for n := 0; n < b.N; n++ {
+        before := heapInUse()
+        interner := NewInterner[string](0)
+        handles := make([]Handle, len(input))
+        for i, value := range input {
+            handles[i] = interner.Intern(value)
+        }
+        b.ReportMetric(float64(heapInUse()-before)/float64(len(input)), "retained-B/value")
+        runtime.KeepAlive(handles)
+        runtime.KeepAlive(interner)
+    }
+}
+
+func BenchmarkMapStrings(b *testing.B) {
+    input := benchInput()
+    b.ReportAllocs()
+    b.ResetTimer()
+    for n := 0; n < b.N; n++ {
+        before := heapInUse()
+        canonical := make(map[string]string)
+        values := make([]string, len(input))
+        for i, value := range input {
+            if c, ok := canonical[value]; ok {
+                values[i] = c
+            } else {
+                canonical[value] = value
+                values[i] = value
+            }
+        }
+        b.ReportMetric(float64(heapInUse()-before)/float64(len(input)), "retained-B/value")
+        runtime.KeepAlive(values)
+        runtime.KeepAlive(canonical)
+    }
+}
+
+func BenchmarkInternerParallel(b *testing.B) {
+    input := benchInput()[:benchDistinct]
+    interner := NewInterner[string](0)
+    b.ReportAllocs()
+    b.ResetTimer()
+    b.RunParallel(func(pb *testing.PB) {
+        i := 0
+        for pb.Next() {
+            interner.Intern(input[i%len(input)])
+            i++
+        }
+    })
+}
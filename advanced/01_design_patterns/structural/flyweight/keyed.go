@@ -0,0 +1,183 @@
+package flyweight
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// Flyweight is any shared object whose operation takes extrinsic state E and returns R
+type Flyweight[E, R any] interface {
+    Operation(extrinsicState E) R
+}
+
+// OperationBatch applies one flyweight's operation to every extrinsic state,
+// returning the results in the same order
+func OperationBatch[E, R any](flyweight Flyweight[E, R], extrinsicStates []E) []R {
+    results := make([]R, len(extrinsicStates))
+    for i, state := range extrinsicStates {
+        results[i] = flyweight.Operation(state)
+    }
+    return results
+}
+
+// KeyedFactory creates and manages flyweights of type F identified by a
+// comparable key K. K is usually a struct holding every intrinsic field, so
+// flyweights that differ in any one field are kept apart.
+type KeyedFactory[K comparable, F any] struct {
+    create     func(K) F
+    flyweights map[K]*entry[K, F]
+    mutex      sync.RWMutex
+    capacity   int
+    // clock holds every entry in insertion order; hand is the next eviction candidate
+    clock     *list.List
+    hand      *list.Element
+    hits      atomic.Int64
+    misses    atomic.Int64
+    evictions atomic.Int64
+}
+
+// entry is a cached flyweight with its eviction bookkeeping
+type entry[K comparable, F any] struct {
+    key        K
+    flyweight  F
+    referenced atomic.Bool
+    refs       atomic.Int64
+}
+
+// factoryConfig holds the settings shared by every factory
+type factoryConfig struct {
+    capacity int
+}
+
+// FactoryOption configures a flyweight factory
+type FactoryOption func(*factoryConfig)
+
+// WithCapacity bounds the number of cached flyweights. Once the factory is full,
+// a new key evicts the least recently used flyweight that is not acquired.
+// Zero, the default, means unbounded.
+func WithCapacity(capacity int) FactoryOption {
+    return func(c *factoryConfig) {
+        c.capacity = capacity
+    }
+}
+
+// FactoryStats reports cache activity of a flyweight factory
+type FactoryStats struct {
+    Hits      int64
+    Misses    int64
+    Evictions int64
+    Live      int
+}
+
+// NewKeyedFactory creates a new KeyedFactory that builds missing flyweights with create
+func NewKeyedFactory[K comparable, F any](create func(K) F, opts ...FactoryOption) *KeyedFactory[K, F] {
+    var config factoryConfig
+    for _, opt := range opts {
+        opt(&config)
+    }
+    return &KeyedFactory[K, F]{
+        create:     create,
+        flyweights: make(map[K]*entry[K, F]),
+        capacity:   config.capacity,
+        clock:      list.New(),
+    }
+}
+
+// Get returns the flyweight for key, creating it on first use
+func (f *KeyedFactory[K, F]) Get(key K) F {
+    return f.get(key, false).flyweight
+}
+
+// Acquire returns the flyweight for key and pins it so it cannot be evicted
+// until release is called. Calling release more than once has no effect.
+func (f *KeyedFactory[K, F]) Acquire(key K) (flyweight F, release func()) {
+    e := f.get(key, true)
+    var released atomic.Bool
+    return e.flyweight, func() {
+        if released.CompareAndSwap(false, true) {
+            e.refs.Add(-1)
+        }
+    }
+}
+
+// Stats returns the factory's hit, miss and eviction counts and the number of live flyweights
+func (f *KeyedFactory[K, F]) Stats() FactoryStats {
+    f.mutex.RLock()
+    live := len(f.flyweights)
+    f.mutex.RUnlock()
+    return FactoryStats{
+        Hits:      f.hits.Load(),
+        Misses:    f.misses.Load(),
+        Evictions: f.evictions.Load(),
+        Live:      live,
+    }
+}
+
+func (f *KeyedFactory[K, F]) get(key K, pin bool) *entry[K, F] {
+    f.mutex.RLock()
+    if e, exists := f.flyweights[key]; exists {
+        // Pinning under the read lock is safe because eviction needs the write lock
+        if pin {
+            e.refs.Add(1)
+        }
+        f.mutex.RUnlock()
+        // Only write the flag when it changes, to keep hot entries' cache lines shared
+        if !e.referenced.Load() {
+            e.referenced.Store(true)
+        }
+        f.hits.Add(1)
+        return e
+    }
+    f.mutex.RUnlock()
+
+    f.mutex.Lock()
+    defer f.mutex.Unlock()
+
+    // Double-check after acquiring write lock
+    if e, exists := f.flyweights[key]; exists {
+        if pin {
+            e.refs.Add(1)
+        }
+        e.referenced.Store(true)
+        f.hits.Add(1)
+        return e
+    }
+
+    f.misses.Add(1)
+    // If everything is pinned the factory goes over capacity until flyweights are released
+    for f.capacity > 0 && len(f.flyweights) >= f.capacity {
+        if !f.evictOne() {
+            break
+        }
+    }
+    e := &entry[K, F]{key: key, flyweight: f.create(key)}
+    if pin {
+        e.refs.Add(1)
+    }
+    f.clock.PushBack(e)
+    f.flyweights[key] = e
+    return e
+}
+
+// evictOne removes one unpinned flyweight using the CLOCK approximation of LRU:
+// the hand skips, and clears, entries used since it last passed them. It
+// returns false if every flyweight is pinned. The caller holds the write lock.
+func (f *KeyedFactory[K, F]) evictOne() bool {
+    for i := 0; i < 2*f.clock.Len(); i++ {
+        if f.hand == nil {
+            f.hand = f.clock.Front()
+        }
+        e := f.hand.Value.(*entry[K, F])
+        next := f.hand.Next()
+        if e.refs.Load() == 0 && !e.referenced.Swap(false) {
+            f.clock.Remove(f.hand)
+            delete(f.flyweights, e.key)
+            f.evictions.Add(1)
+            f.hand = next
+            return true
+        }
+        f.hand = next
+    }
+    return false
+}
@@ -0,0 +1,72 @@
+package flyweight
+
+import (
+	"reflect"
+	"testing"
+)
+
+type styleKey struct {
+    font string
+    size int
+    bold bool
+}
+
+type style struct {
+    key styleKey
+}
+
+func (s *style) Operation(text string) string {
+    if s.key.bold {
+        return "**" + text + "**"
+    }
+    return text
+}
+
+func TestKeyedFactoryStructKeys(t *testing.T) {
+    created := 0
+    factory := NewKeyedFactory(func(key styleKey) *style {
+        created++
+        return &style{key: key}
+    })
+
+    regular := factory.Get(styleKey{"serif", 12, false})
+    bold := factory.Get(styleKey{"serif", 12, true})
+    again := factory.Get(styleKey{"serif", 12, false})
+
+    if regular != again {
+        t.Error("Expected equal keys to share a flyweight")
+    }
+    if regular == bold {
+        t.Error("Expected keys differing in one field to get different flyweights")
+    }
+    if created != 2 {
+        t.Errorf("Expected 2 flyweights to be created, got %d", created)
+    }
+}
+
+func TestKeyedFactoryCapacity(t *testing.T) {
+    factory := NewKeyedFactory(func(key styleKey) *style { return &style{key: key} }, WithCapacity(1))
+    factory.Get(styleKey{"serif", 12, false})
+    factory.Get(styleKey{"mono", 10, false})
+
+    if stats := factory.Stats(); stats.Live != 1 || stats.Evictions != 1 {
+        t.Errorf("Expected 1 live flyweight and 1 eviction, got %+v", stats)
+    }
+}
+
+func TestOperationBatch(t *testing.T) {
+    bold := &style{key: styleKey{"serif", 12, true}}
+    expected := []string{"**a**", "**b**"}
+    if result := OperationBatch[string, string](bold, []string{"a", "b"}); !reflect.DeepEqual(result, expected) {
+        t.Errorf("Expected %v, got %v", expected, result)
+    }
+
+    flyweight := NewConcreteFlyweight("shared")
+    expected = []string{"Intrinsic: shared, Extrinsic: x", "Intrinsic: shared, Extrinsic: y"}
+    if result := flyweight.OperationBatch([]string{"x", "y"}); !reflect.DeepEqual(result, expected) {
+        t.Errorf("Expected %v, got %v", expected, result)
+    }
+    if result := flyweight.OperationBatch(nil); len(result) != 0 {
+        t.Errorf("Expected no results, got %v", result)
+    }
+}
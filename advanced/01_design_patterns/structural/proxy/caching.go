@@ -0,0 +1,214 @@
+package proxy
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock abstracts time so tests can control cache expiry
+type Clock interface {
+    Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// LoaderFunc produces the value for a key, e.g. by calling the real subject
+type LoaderFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// CacheConfig configures a CachingProxy
+type CacheConfig struct {
+    // TTL is how long a loaded value is fresh
+    TTL time.Duration
+    // StaleTTL is how long after TTL a value may still be served while it is
+    // refreshed in the background. Zero disables stale-while-revalidate.
+    StaleTTL time.Duration
+    // ErrorTTL is how long a loader error is cached. Zero disables negative caching.
+    ErrorTTL time.Duration
+    // MaxEntries bounds the cache, evicting the least recently used entry. Zero means unbounded.
+    MaxEntries int
+    Clock      Clock
+}
+
+// CacheStats reports what a CachingProxy has done so far
+type CacheStats struct {
+    Hits      int
+    StaleHits int
+    Misses    int
+    Loads     int
+    Coalesced int
+    Evictions int
+}
+
+// CachingProxy caches the results of a loader per key. Concurrent misses for
+// the same key share one load, and the cache lock is never held while the
+// loader runs.
+type CachingProxy[K comparable, V any] struct {
+    loader   LoaderFunc[K, V]
+    config   CacheConfig
+    clock    Clock
+    mu       sync.Mutex
+    entries  map[K]*list.Element
+    lru      *list.List
+    inflight map[K]*load[V]
+    stats    CacheStats
+}
+
+type cacheEntry[K comparable, V any] struct {
+    key     K
+    value   V
+    err     error
+    expires time.Time
+}
+
+// load is a loader call that concurrent callers wait on together
+type load[V any] struct {
+    done  chan struct{}
+    value V
+    err   error
+}
+
+// NewCachingProxy creates a new CachingProxy
+func NewCachingProxy[K comparable, V any](loader LoaderFunc[K, V], config CacheConfig) *CachingProxy[K, V] {
+    clock := config.Clock
+    if clock == nil {
+        clock = systemClock{}
+    }
+    return &CachingProxy[K, V]{
+        loader:   loader,
+        config:   config,
+        clock:    clock,
+        entries:  make(map[K]*list.Element),
+        lru:      list.New(),
+        inflight: make(map[K]*load[V]),
+    }
+}
+
+// Get returns the value for key, from the cache when possible. Errors cached
+// by negative caching are returned like fresh ones.
+func (p *CachingProxy[K, V]) Get(ctx context.Context, key K) (V, error) {
+    p.mu.Lock()
+    if element, ok := p.entries[key]; ok {
+        entry := element.Value.(*cacheEntry[K, V])
+        now := p.clock.Now()
+        if now.Before(entry.expires) {
+            p.lru.MoveToFront(element)
+            p.stats.Hits++
+            p.mu.Unlock()
+            return entry.value, entry.err
+        }
+        if entry.err == nil && now.Before(entry.expires.Add(p.config.StaleTTL)) {
+            p.lru.MoveToFront(element)
+            p.stats.StaleHits++
+            p.startLoad(ctx, key)
+            p.mu.Unlock()
+            return entry.value, nil
+        }
+    }
+    p.stats.Misses++
+    l := p.startLoad(ctx, key)
+    p.mu.Unlock()
+
+    select {
+    case <-l.done:
+        return l.value, l.err
+    case <-ctx.Done():
+        var zero V
+        return zero, ctx.Err()
+    }
+}
+
+// Invalidate removes key from the cache
+func (p *CachingProxy[K, V]) Invalidate(key K) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if element, ok := p.entries[key]; ok {
+        p.lru.Remove(element)
+        delete(p.entries, key)
+    }
+}
+
+// Len returns the number of cached entries, including expired ones not yet replaced
+func (p *CachingProxy[K, V]) Len() int {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    return p.lru.Len()
+}
+
+// Stats returns a copy of the proxy's counters
+func (p *CachingProxy[K, V]) Stats() CacheStats {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    return p.stats
+}
+
+// startLoad joins the load in flight for key or starts a new one. The loader
+// runs detached from the caller's cancellation so one impatient caller cannot
+// fail the load for everyone waiting on it. The caller holds p.mu.
+func (p *CachingProxy[K, V]) startLoad(ctx context.Context, key K) *load[V] {
+    if l, ok := p.inflight[key]; ok {
+        p.stats.Coalesced++
+        return l
+    }
+    l := &load[V]{done: make(chan struct{})}
+    p.inflight[key] = l
+    p.stats.Loads++
+    go func() {
+        value, err := p.callLoader(context.WithoutCancel(ctx), key)
+        p.mu.Lock()
+        l.value, l.err = value, err
+        delete(p.inflight, key)
+        p.store(key, value, err)
+        p.mu.Unlock()
+        close(l.done)
+    }()
+    return l
+}
+
+// callLoader runs the loader, turning a panic into the load's error so the
+// waiters on the load are always released
+func (p *CachingProxy[K, V]) callLoader(ctx context.Context, key K) (value V, err error) {
+    defer func() {
+        if r := recover(); r != nil {
+            var zero V
+            value, err = zero, fmt.Errorf("proxy: loader panicked: %v", r)
+        }
+    }()
+    return p.loader(ctx, key)
+}
+
+// store caches the outcome of a load. The caller holds p.mu.
+func (p *CachingProxy[K, V]) store(key K, value V, err error) {
+    ttl := p.config.TTL
+    element, exists := p.entries[key]
+    if err != nil {
+        ttl = p.config.ErrorTTL
+        // A failed refresh keeps serving the stale value rather than replacing it with the error
+        if exists {
+            previous := element.Value.(*cacheEntry[K, V])
+            if previous.err == nil && p.clock.Now().Before(previous.expires.Add(p.config.StaleTTL)) {
+                return
+            }
+        }
+    }
+    if ttl <= 0 {
+        return
+    }
+    entry := &cacheEntry[K, V]{key: key, value: value, err: err, expires: p.clock.Now().Add(ttl)}
+    if exists {
+        element.Value = entry
+        p.lru.MoveToFront(element)
+        return
+    }
+    p.entries[key] = p.lru.PushFront(entry)
+    if p.config.MaxEntries > 0 && p.lru.Len() > p.config.MaxEntries {
+        oldest := p.lru.Back()
+        p.lru.Remove(oldest)
+        delete(p.entries, oldest.Value.(*cacheEntry[K, V]).key)
+        p.stats.Evictions++
+    }
+}
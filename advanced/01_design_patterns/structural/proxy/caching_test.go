@@ -0,0 +1,283 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when the test advances it
+type fakeClock struct {
+    mu  sync.Mutex
+    now time.Time
+}
+
+func newFakeClock() *fakeClock {
+    return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.now = c.now.Add(d)
+}
+
+// countingLoader returns "<key>#<n>" where n counts the loads so far
+type countingLoader struct {
+    calls atomic.Int64
+    err   error
+}
+
+func (l *countingLoader) Load(ctx context.Context, key string) (string, error) {
+    n := l.calls.Add(1)
+    if l.err != nil {
+        return "", l.err
+    }
+    return fmt.Sprintf("%s#%d", key, n), nil
+}
+
+func TestCachingProxyPerKey(t *testing.T) {
+    loader := &countingLoader{}
+    cache := NewCachingProxy(loader.Load, CacheConfig{TTL: time.Minute, Clock: newFakeClock()})
+    ctx := context.Background()
+
+    a1, _ := cache.Get(ctx, "a")
+    b1, _ := cache.Get(ctx, "b")
+    a2, _ := cache.Get(ctx, "a")
+    if a1 != "a#1" || b1 != "b#2" || a2 != "a#1" {
+        t.Errorf("Unexpected values %s, %s, %s", a1, b1, a2)
+    }
+    if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 2 {
+        t.Errorf("Unexpected stats %+v", stats)
+    }
+}
+
+func TestCachingProxyTTL(t *testing.T) {
+    clock := newFakeClock()
+    loader := &countingLoader{}
+    cache := NewCachingProxy(loader.Load, CacheConfig{TTL: time.Minute, Clock: clock})
+    ctx := context.Background()
+
+    cache.Get(ctx, "a")
+    clock.Advance(30 * time.Second)
+    cache.Get(ctx, "b")
+    clock.Advance(31 * time.Second)
+
+    // "a" has expired, "b" has not
+    if value, _ := cache.Get(ctx, "a"); value != "a#3" {
+        t.Errorf("Expected 'a' to reload, got %s", value)
+    }
+    if value, _ := cache.Get(ctx, "b"); value != "b#2" {
+        t.Errorf("Expected 'b' to be cached, got %s", value)
+    }
+}
+
+func TestCachingProxyBounded(t *testing.T) {
+    loader := &countingLoader{}
+    cache := NewCachingProxy(loader.Load, CacheConfig{TTL: time.Minute, MaxEntries: 2, Clock: newFakeClock()})
+    ctx := context.Background()
+
+    cache.Get(ctx, "a")
+    cache.Get(ctx, "b")
+    cache.Get(ctx, "a")
+    cache.Get(ctx, "c")
+
+    if cache.Len() != 2 || cache.Stats().Evictions != 1 {
+        t.Fatalf("Expected 2 entries and 1 eviction, got %d and %+v", cache.Len(), cache.Stats())
+    }
+    if value, _ := cache.Get(ctx, "a"); value != "a#1" {
+        t.Errorf("Expected recently used 'a' to stay cached, got %s", value)
+    }
+    if value, _ := cache.Get(ctx, "b"); value != "b#4" {
+        t.Errorf("Expected 'b' to be evicted and reloaded, got %s", value)
+    }
+}
+
+func TestCachingProxyCoalescesMisses(t *testing.T) {
+    release := make(chan struct{})
+    var calls atomic.Int64
+    cache := NewCachingProxy(func(ctx context.Context, key string) (string, error) {
+        calls.Add(1)
+        <-release
+        return "value", nil
+    }, CacheConfig{TTL: time.Minute})
+
+    var wg sync.WaitGroup
+    for i := 0; i < 10; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if value, err := cache.Get(context.Background(), "key"); value != "value" || err != nil {
+                t.Errorf("Unexpected result %s, %v", value, err)
+            }
+        }()
+    }
+    for cache.Stats().Misses < 10 {
+        time.Sleep(time.Millisecond)
+    }
+    close(release)
+    wg.Wait()
+
+    if calls.Load() != 1 {
+        t.Errorf("Expected a single load, got %d", calls.Load())
+    }
+    if stats := cache.Stats(); stats.Coalesced != 9 {
+        t.Errorf("Expected 9 coalesced misses, got %d", stats.Coalesced)
+    }
+}
+
+func TestCachingProxyLoaderPanic(t *testing.T) {
+    release := make(chan struct{})
+    cache := NewCachingProxy(func(ctx context.Context, key string) (string, error) {
+        <-release
+        panic("loader bug")
+    }, CacheConfig{TTL: time.Minute})
+
+    errs := make(chan error, 3)
+    for i := 0; i < 3; i++ {
+        go func() {
+            _, err := cache.Get(context.Background(), "key")
+            errs <- err
+        }()
+    }
+    for cache.Stats().Misses < 3 {
+        time.Sleep(time.Millisecond)
+    }
+    close(release)
+
+    for i := 0; i < 3; i++ {
+        select {
+        case err := <-errs:
+            if err == nil || !strings.Contains(err.Error(), "loader bug") {
+                t.Errorf("Expected the panic as an error, got %v", err)
+            }
+        case <-time.After(2 * time.Second):
+            t.Fatal("Waiter blocked after the loader panicked")
+        }
+    }
+}
+
+func TestCachingProxyCallerCancellation(t *testing.T) {
+    release := make(chan struct{})
+    cache := NewCachingProxy(func(ctx context.Context, key string) (string, error) {
+        <-release
+        return "value", ctx.Err()
+    }, CacheConfig{TTL: time.Minute})
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if _, err := cache.Get(ctx, "key"); !errors.Is(err, context.Canceled) {
+        t.Fatalf("Expected context.Canceled, got %v", err)
+    }
+
+    // The load keeps going and serves the next caller
+    close(release)
+    if value, err := cache.Get(context.Background(), "key"); value != "value" || err != nil {
+        t.Errorf("Expected the detached load to succeed, got %s, %v", value, err)
+    }
+}
+
+func TestCachingProxyStaleWhileRevalidate(t *testing.T) {
+    clock := newFakeClock()
+    refreshed := make(chan struct{}, 1)
+    loader := &countingLoader{}
+    cache := NewCachingProxy(func(ctx context.Context, key string) (string, error) {
+        defer func() { refreshed <- struct{}{} }()
+        return loader.Load(ctx, key)
+    }, CacheConfig{TTL: time.Minute, StaleTTL: time.Minute, Clock: clock})
+    ctx := context.Background()
+
+    cache.Get(ctx, "a")
+    <-refreshed
+    clock.Advance(90 * time.Second)
+
+    if value, _ := cache.Get(ctx, "a"); value != "a#1" {
+        t.Errorf("Expected the stale value, got %s", value)
+    }
+    <-refreshed
+    if value, _ := cache.Get(ctx, "a"); value != "a#2" {
+        t.Errorf("Expected the refreshed value, got %s", value)
+    }
+    if stats := cache.Stats(); stats.StaleHits != 1 {
+        t.Errorf("Expected 1 stale hit, got %d", stats.StaleHits)
+    }
+
+    // Past the stale window the caller waits for a fresh load
+    clock.Advance(3 * time.Minute)
+    if value, _ := cache.Get(ctx, "a"); value != "a#3" {
+        t.Errorf("Expected a blocking reload, got %s", value)
+    }
+}
+
+func TestCachingProxyNegativeCaching(t *testing.T) {
+    clock := newFakeClock()
+    errDown := errors.New("down")
+    loader := &countingLoader{err: errDown}
+    cache := NewCachingProxy(loader.Load, CacheConfig{TTL: time.Minute, ErrorTTL: 10 * time.Second, Clock: clock})
+    ctx := context.Background()
+
+    for i := 0; i < 3; i++ {
+        if _, err := cache.Get(ctx, "a"); !errors.Is(err, errDown) {
+            t.Fatalf("Expected errDown, got %v", err)
+        }
+    }
+    if loader.calls.Load() != 1 {
+        t.Errorf("Expected the error to be cached, got %d loads", loader.calls.Load())
+    }
+
+    clock.Advance(11 * time.Second)
+    cache.Get(ctx, "a")
+    if loader.calls.Load() != 2 {
+        t.Errorf("Expected a retry after ErrorTTL, got %d loads", loader.calls.Load())
+    }
+}
+
+func TestCachingProxyNoNegativeCaching(t *testing.T) {
+    loader := &countingLoader{err: errors.New("down")}
+    cache := NewCachingProxy(loader.Load, CacheConfig{TTL: time.Minute, Clock: newFakeClock()})
+    cache.Get(context.Background(), "a")
+    cache.Get(context.Background(), "a")
+    if loader.calls.Load() != 2 {
+        t.Errorf("Expected errors not to be cached, got %d loads", loader.calls.Load())
+    }
+}
+
+func TestCachingProxyInvalidate(t *testing.T) {
+    loader := &countingLoader{}
+    cache := NewCachingProxy(loader.Load, CacheConfig{TTL: time.Minute, Clock: newFakeClock()})
+    cache.Get(context.Background(), "a")
+    cache.Invalidate("a")
+    if value, _ := cache.Get(context.Background(), "a"); value != "a#2" {
+        t.Errorf("Expected a reload after Invalidate, got %s", value)
+    }
+}
+
+func TestProxyDoesNotBlockOnSlowSubject(t *testing.T) {
+    // Proxy is built on CachingProxy, so concurrent first requests share one call
+    proxy := NewProxy(NewRealSubject("test"), time.Minute)
+    var wg sync.WaitGroup
+    for i := 0; i < 5; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if result := proxy.Request(); result != "RealSubject test request" {
+                t.Errorf("Unexpected result '%s'", result)
+            }
+        }()
+    }
+    wg.Wait()
+    if stats := proxy.cache.Stats(); stats.Loads != 1 {
+        t.Errorf("Expected one load, got %d", stats.Loads)
+    }
+}
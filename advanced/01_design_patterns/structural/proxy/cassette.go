@@ -0,0 +1,292 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnmatchedCall is matched by every UnmatchedCallError
+var ErrUnmatchedCall = errors.New("proxy: no recorded interaction")
+
+// UnmatchedCallError is returned when a replayed call has no recorded interaction left
+type UnmatchedCallError struct {
+    Method string
+    Args   string
+}
+
+func (e *UnmatchedCallError) Error() string {
+    return fmt.Sprintf("proxy: no recorded interaction for %s(%s)", e.Method, e.Args)
+}
+
+// Is makes errors.Is(err, ErrUnmatchedCall) match
+func (e *UnmatchedCallError) Is(target error) bool {
+    return target == ErrUnmatchedCall
+}
+
+// RecordedError is a replayed error. Only the message is recorded, so compare
+// replayed errors by text rather than with errors.Is.
+type RecordedError struct {
+    Message string
+}
+
+func (e *RecordedError) Error() string {
+    return e.Message
+}
+
+// CallFunc is one method of a subject, taking arguments A and returning R
+type CallFunc[A, R any] func(ctx context.Context, args A) (R, error)
+
+// Interaction is one recorded call
+type Interaction struct {
+    Method string          `json:"method"`
+    Args   json.RawMessage `json:"args"`
+    Result json.RawMessage `json:"result,omitempty"`
+    Error  string          `json:"error,omitempty"`
+    // DurationMS is how long the real call took, in milliseconds. It is
+    // only recorded with RecordConfig.Timing.
+    DurationMS int64 `json:"durationMs,omitempty"`
+}
+
+// Cassette holds recorded interactions. Record fills it from real calls and
+// Replay serves calls from it. Calls with the same method and arguments are
+// replayed in the order they were recorded, each one once.
+type Cassette struct {
+    mu           sync.Mutex
+    interactions []Interaction
+    used         []bool
+}
+
+// NewCassette creates an empty Cassette for recording
+func NewCassette() *Cassette {
+    return &Cassette{}
+}
+
+// LoadCassette reads a cassette written by Save
+func LoadCassette(path string) (*Cassette, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var file struct {
+        Interactions []Interaction `json:"interactions"`
+    }
+    if err := json.Unmarshal(data, &file); err != nil {
+        return nil, fmt.Errorf("proxy: parse cassette %s: %w", path, err)
+    }
+    // Save indents the arguments, so compact them again to match the JSON calls encode
+    for i, interaction := range file.Interactions {
+        if len(bytes.TrimSpace(interaction.Args)) == 0 {
+            // A call without arguments may have been saved without any
+            file.Interactions[i].Args = json.RawMessage("null")
+            continue
+        }
+        var args bytes.Buffer
+        if err := json.Compact(&args, interaction.Args); err != nil {
+            return nil, fmt.Errorf("proxy: parse cassette %s: %w", path, err)
+        }
+        file.Interactions[i].Args = args.Bytes()
+    }
+    return &Cassette{interactions: file.Interactions, used: make([]bool, len(file.Interactions))}, nil
+}
+
+// Save writes the cassette to path. Interactions are sorted by method and
+// arguments, keeping call order within each, so recording the same calls
+// again gives the same file. Recording with RecordConfig.Timing adds
+// wall-clock timings, which differ from run to run.
+func (c *Cassette) Save(path string) error {
+    c.mu.Lock()
+    interactions := append([]Interaction(nil), c.interactions...)
+    c.mu.Unlock()
+    sort.SliceStable(interactions, func(i, j int) bool {
+        if interactions[i].Method != interactions[j].Method {
+            return interactions[i].Method < interactions[j].Method
+        }
+        return bytes.Compare(interactions[i].Args, interactions[j].Args) < 0
+    })
+
+    data, err := json.MarshalIndent(struct {
+        Interactions []Interaction `json:"interactions"`
+    }{interactions}, "", "  ")
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Interactions returns a copy of the recorded interactions in call order
+func (c *Cassette) Interactions() []Interaction {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return append([]Interaction(nil), c.interactions...)
+}
+
+// Unused returns the interactions that have not been replayed, so tests can
+// check that every recorded call still happens
+func (c *Cassette) Unused() []Interaction {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    var unused []Interaction
+    for i, interaction := range c.interactions {
+        if !c.used[i] {
+            unused = append(unused, interaction)
+        }
+    }
+    return unused
+}
+
+func (c *Cassette) add(interaction Interaction) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.interactions = append(c.interactions, interaction)
+    c.used = append(c.used, false)
+}
+
+// take marks the first unused interaction matching method and args as used and returns it
+func (c *Cassette) take(method string, args []byte) (Interaction, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    for i, interaction := range c.interactions {
+        if !c.used[i] && interaction.Method == method && sameArgs(interaction.Args, args) {
+            c.used[i] = true
+            return interaction, true
+        }
+    }
+    return Interaction{}, false
+}
+
+// sameArgs reports whether recorded arguments match a call's. Null, empty
+// objects and empty arrays all mean "no arguments" and match each other.
+func sameArgs(recorded, call []byte) bool {
+    return bytes.Equal(recorded, call) || noArgs(recorded) && noArgs(call)
+}
+
+func noArgs(args []byte) bool {
+    switch string(args) {
+    case "", "null", "{}", "[]":
+        return true
+    }
+    return false
+}
+
+// RecordConfig configures Record
+type RecordConfig struct {
+    // Timing records how long each real call took, for ReplayConfig.Latency.
+    // It is off by default because timings change the cassette on every run.
+    Timing bool
+}
+
+// Record wraps call so every call is forwarded and recorded on cassette under method
+func Record[A, R any](cassette *Cassette, method string, config RecordConfig, call CallFunc[A, R]) CallFunc[A, R] {
+    return func(ctx context.Context, args A) (R, error) {
+        encodedArgs, err := json.Marshal(args)
+        if err != nil {
+            var zero R
+            return zero, fmt.Errorf("proxy: record %s args: %w", method, err)
+        }
+        start := time.Now()
+        result, callErr := call(ctx, args)
+        interaction := Interaction{Method: method, Args: encodedArgs}
+        if config.Timing {
+            interaction.DurationMS = time.Since(start).Milliseconds()
+        }
+        if callErr != nil {
+            interaction.Error = callErr.Error()
+        } else if interaction.Result, err = json.Marshal(result); err != nil {
+            return result, fmt.Errorf("proxy: record %s result: %w", method, err)
+        }
+        cassette.add(interaction)
+        return result, callErr
+    }
+}
+
+// ReplayConfig configures Replay
+type ReplayConfig struct {
+    // Latency makes each replayed call wait as long as the recorded one did
+    Latency bool
+}
+
+// Replay returns a call that serves method from cassette without a real subject.
+// A call with no matching interaction left fails with an *UnmatchedCallError.
+func Replay[A, R any](cassette *Cassette, method string, config ReplayConfig) CallFunc[A, R] {
+    return func(ctx context.Context, args A) (R, error) {
+        var zero R
+        encodedArgs, err := json.Marshal(args)
+        if err != nil {
+            return zero, fmt.Errorf("proxy: replay %s args: %w", method, err)
+        }
+        interaction, ok := cassette.take(method, encodedArgs)
+        if !ok {
+            return zero, &UnmatchedCallError{Method: method, Args: string(encodedArgs)}
+        }
+        if config.Latency && interaction.DurationMS > 0 {
+            timer := time.NewTimer(time.Duration(interaction.DurationMS) * time.Millisecond)
+            select {
+            case <-timer.C:
+            case <-ctx.Done():
+                timer.Stop()
+                return zero, ctx.Err()
+            }
+        }
+        if interaction.Error != "" {
+            return zero, &RecordedError{Message: interaction.Error}
+        }
+        var result R
+        if err := json.Unmarshal(interaction.Result, &result); err != nil {
+            return zero, fmt.Errorf("proxy: replay %s result: %w", method, err)
+        }
+        return result, nil
+    }
+}
+
+// requestMethod is the cassette method name for Subject.Request
+const requestMethod = "Request"
+
+// RecordingSubject is a Subject that forwards to a real subject and records each call
+type RecordingSubject struct {
+    call CallFunc[struct{}, string]
+}
+
+// NewRecordingSubject creates a new RecordingSubject recording subject's calls on cassette
+func NewRecordingSubject(subject Subject, cassette *Cassette, config RecordConfig) *RecordingSubject {
+    return &RecordingSubject{call: Record(cassette, requestMethod, config, func(ctx context.Context, _ struct{}) (string, error) {
+        return subject.Request(), nil
+    })}
+}
+
+// Request implements the Subject interface
+func (s *RecordingSubject) Request() string {
+    result, _ := s.call(context.Background(), struct{}{})
+    return result
+}
+
+// ReplayingSubject is a Subject that serves calls recorded by a RecordingSubject
+type ReplayingSubject struct {
+    call CallFunc[struct{}, string]
+}
+
+// NewReplayingSubject creates a new ReplayingSubject serving calls from cassette
+func NewReplayingSubject(cassette *Cassette, config ReplayConfig) *ReplayingSubject {
+    return &ReplayingSubject{call: Replay[struct{}, string](cassette, requestMethod, config)}
+}
+
+// Call returns the next recorded result, or an *UnmatchedCallError once the recorded calls run out
+func (s *ReplayingSubject) Call(ctx context.Context) (string, error) {
+    return s.call(ctx, struct{}{})
+}
+
+// Request implements the Subject interface. It panics on an unmatched call so
+// a test that drifts from its cassette fails loudly; use Call to get the error.
+func (s *ReplayingSubject) Request() string {
+    result, err := s.Call(context.Background())
+    if err != nil {
+        panic(err)
+    }
+    return result
+}
@@ -0,0 +1,252 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// inventory is a slow service with several methods, standing in for a real subject
+type inventory struct {
+    stock map[string]int
+}
+
+type reserveArgs struct {
+    SKU      string `json:"sku"`
+    Quantity int    `json:"quantity"`
+}
+
+func (s *inventory) Stock(ctx context.Context, sku string) (int, error) {
+    count, ok := s.stock[sku]
+    if !ok {
+        return 0, fmt.Errorf("unknown sku %s", sku)
+    }
+    return count, nil
+}
+
+func (s *inventory) Reserve(ctx context.Context, args reserveArgs) (int, error) {
+    if s.stock[args.SKU] < args.Quantity {
+        return 0, errors.New("insufficient stock")
+    }
+    s.stock[args.SKU] -= args.Quantity
+    return s.stock[args.SKU], nil
+}
+
+// inventoryCalls holds one CallFunc per inventory method, so a test can use
+// either the recording or the replaying side
+type inventoryCalls struct {
+    stock   CallFunc[string, int]
+    reserve CallFunc[reserveArgs, int]
+}
+
+func recordInventory(cassette *Cassette) inventoryCalls {
+    real := &inventory{stock: map[string]int{"apple": 5, "pear": 1}}
+    return inventoryCalls{
+        stock:   Record(cassette, "Stock", RecordConfig{}, real.Stock),
+        reserve: Record(cassette, "Reserve", RecordConfig{}, real.Reserve),
+    }
+}
+
+func replayInventory(cassette *Cassette) inventoryCalls {
+    return inventoryCalls{
+        stock:   Replay[string, int](cassette, "Stock", ReplayConfig{}),
+        reserve: Replay[reserveArgs, int](cassette, "Reserve", ReplayConfig{}),
+    }
+}
+
+func runInventoryScenario(calls inventoryCalls) []string {
+    ctx := context.Background()
+    var out []string
+    add := func(value int, err error) {
+        out = append(out, fmt.Sprintf("%d %v", value, err))
+    }
+    add(calls.stock(ctx, "apple"))
+    add(calls.reserve(ctx, reserveArgs{SKU: "apple", Quantity: 2}))
+    add(calls.reserve(ctx, reserveArgs{SKU: "apple", Quantity: 2}))
+    add(calls.reserve(ctx, reserveArgs{SKU: "pear", Quantity: 3}))
+    add(calls.stock(ctx, "plum"))
+    return out
+}
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "inventory.json")
+    recording := NewCassette()
+    recorded := runInventoryScenario(recordInventory(recording))
+    if err := recording.Save(path); err != nil {
+        t.Fatal(err)
+    }
+
+    cassette, err := LoadCassette(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    replayed := runInventoryScenario(replayInventory(cassette))
+    for i := range recorded {
+        if recorded[i] != replayed[i] {
+            t.Errorf("Call %d: recorded '%s', replayed '%s'", i, recorded[i], replayed[i])
+        }
+    }
+    if unused := cassette.Unused(); len(unused) != 0 {
+        t.Errorf("Expected every interaction to be replayed, got %d unused", len(unused))
+    }
+}
+
+func TestCassetteReplaysRepeatedCallsInOrder(t *testing.T) {
+    recording := NewCassette()
+    calls := recordInventory(recording)
+    ctx := context.Background()
+    calls.reserve(ctx, reserveArgs{SKU: "apple", Quantity: 1})
+    calls.reserve(ctx, reserveArgs{SKU: "apple", Quantity: 1})
+
+    replay := replayInventory(recording)
+    first, _ := replay.reserve(ctx, reserveArgs{SKU: "apple", Quantity: 1})
+    second, _ := replay.reserve(ctx, reserveArgs{SKU: "apple", Quantity: 1})
+    if first != 4 || second != 3 {
+        t.Errorf("Expected 4 then 3, got %d then %d", first, second)
+    }
+
+    // A third identical call was never recorded
+    _, err := replay.reserve(ctx, reserveArgs{SKU: "apple", Quantity: 1})
+    var unmatched *UnmatchedCallError
+    if !errors.As(err, &unmatched) || !errors.Is(err, ErrUnmatchedCall) {
+        t.Fatalf("Expected an UnmatchedCallError, got %v", err)
+    }
+    if unmatched.Method != "Reserve" || unmatched.Args != `{"sku":"apple","quantity":1}` {
+        t.Errorf("Unexpected error details %+v", unmatched)
+    }
+}
+
+func TestCassetteUnmatchedArgs(t *testing.T) {
+    recording := NewCassette()
+    recordInventory(recording).stock(context.Background(), "apple")
+
+    replay := replayInventory(recording)
+    if _, err := replay.stock(context.Background(), "pear"); !errors.Is(err, ErrUnmatchedCall) {
+        t.Errorf("Expected ErrUnmatchedCall for different args, got %v", err)
+    }
+    if len(recording.Unused()) != 1 {
+        t.Errorf("Expected the apple call to remain unused")
+    }
+}
+
+func TestCassetteReplaysErrors(t *testing.T) {
+    recording := NewCassette()
+    recordInventory(recording).stock(context.Background(), "plum")
+
+    _, err := replayInventory(recording).stock(context.Background(), "plum")
+    var recorded *RecordedError
+    if !errors.As(err, &recorded) || err.Error() != "unknown sku plum" {
+        t.Errorf("Expected the recorded error, got %v", err)
+    }
+}
+
+func TestCassetteSaveIsStable(t *testing.T) {
+    dir := t.TempDir()
+    ctx := context.Background()
+
+    // The same calls made in a different order produce the same file
+    first := NewCassette()
+    calls := recordInventory(first)
+    calls.stock(ctx, "apple")
+    calls.stock(ctx, "pear")
+    calls.reserve(ctx, reserveArgs{SKU: "pear", Quantity: 1})
+
+    second := NewCassette()
+    calls = recordInventory(second)
+    calls.stock(ctx, "pear")
+    calls.reserve(ctx, reserveArgs{SKU: "pear", Quantity: 1})
+    calls.stock(ctx, "apple")
+
+    first.Save(filepath.Join(dir, "first.json"))
+    second.Save(filepath.Join(dir, "second.json"))
+    a, _ := os.ReadFile(filepath.Join(dir, "first.json"))
+    b, _ := os.ReadFile(filepath.Join(dir, "second.json"))
+    if !bytes.Equal(a, b) {
+        t.Errorf("Expected identical cassettes, got\n%s\nand\n%s", a, b)
+    }
+    if bytes.Contains(a, []byte("durationMs")) {
+        t.Errorf("Expected no timings without RecordConfig.Timing, got\n%s", a)
+    }
+}
+
+func TestCassetteRecordsTiming(t *testing.T) {
+    cassette := NewCassette()
+    slow := Record(cassette, "Slow", RecordConfig{Timing: true}, func(ctx context.Context, _ struct{}) (string, error) {
+        time.Sleep(20 * time.Millisecond)
+        return "done", nil
+    })
+    slow(context.Background(), struct{}{})
+    if interactions := cassette.Interactions(); len(interactions) != 1 || interactions[0].DurationMS < 20 {
+        t.Fatalf("Expected a recorded duration of at least 20ms, got %+v", interactions)
+    }
+
+    // With Latency, replay waits as long as the recording did, but honors ctx
+    replay := Replay[struct{}, string](cassette, "Slow", ReplayConfig{Latency: true})
+    ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+    defer cancel()
+    if _, err := replay(ctx, struct{}{}); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+    }
+}
+
+func TestLoadCassetteErrors(t *testing.T) {
+    dir := t.TempDir()
+    if _, err := LoadCassette(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+        t.Errorf("Expected os.ErrNotExist, got %v", err)
+    }
+    path := filepath.Join(dir, "broken.json")
+    os.WriteFile(path, []byte("{"), 0o644)
+    if _, err := LoadCassette(path); err == nil {
+        t.Error("Expected a parse error")
+    }
+}
+
+func TestCassetteWithoutArgsRoundTrip(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "noargs.json")
+    os.WriteFile(path, []byte(`{"interactions": [
+        {"method": "Ping", "result": "pong"},
+        {"method": "Ping", "args": null, "result": "pong again"}
+    ]}`), 0o644)
+
+    for round := 0; round < 2; round++ {
+        cassette, err := LoadCassette(path)
+        if err != nil {
+            t.Fatalf("Round %d: unexpected error: %v", round, err)
+        }
+        ping := Replay[struct{}, string](cassette, "Ping", ReplayConfig{})
+        for _, expected := range []string{"pong", "pong again"} {
+            if result, err := ping(context.Background(), struct{}{}); err != nil || result != expected {
+                t.Errorf("Round %d: expected '%s', got '%s', %v", round, expected, result, err)
+            }
+        }
+        if err := cassette.Save(path); err != nil {
+            t.Fatalf("Round %d: unexpected save error: %v", round, err)
+        }
+    }
+}
+
+func TestRecordingAndReplayingSubject(t *testing.T) {
+    cassette := NewCassette()
+    client := NewClient(NewRecordingSubject(NewRealSubject("slow"), cassette, RecordConfig{}))
+    expected := "RealSubject slow request"
+    if result := client.UseSubject(); result != expected {
+        t.Fatalf("Expected '%s', got '%s'", expected, result)
+    }
+
+    replaying := NewReplayingSubject(cassette, ReplayConfig{})
+    if result := NewClient(replaying).UseSubject(); result != expected {
+        t.Errorf("Expected '%s', got '%s'", expected, result)
+    }
+
+    defer func() {
+        if r := recover(); r == nil {
+            t.Error("Expected an unmatched Request to panic")
+        }
+    }()
+    replaying.Request()
+}
@@ -0,0 +1,223 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// OperationRequest is the operation name ProtectionProxy checks before Request
+const OperationRequest = "request"
+
+// WildcardPermission granted to a role allows every operation's permission
+const WildcardPermission = "*"
+
+// ErrAccessDenied is matched by every AccessDeniedError
+var ErrAccessDenied = errors.New("proxy: access denied")
+
+// AccessDeniedError is returned when the policy does not allow a call
+type AccessDeniedError struct {
+    Principal string
+    Operation string
+    Reason    string
+}
+
+func (e *AccessDeniedError) Error() string {
+    return fmt.Sprintf("proxy: access denied for %s to %s: %s", e.Principal, e.Operation, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrAccessDenied) match
+func (e *AccessDeniedError) Is(target error) bool {
+    return target == ErrAccessDenied
+}
+
+// Principal is the caller a ProtectionProxy authorizes, e.g. a user or service
+type Principal struct {
+    ID    string
+    Roles []string
+    // Scopes are what the caller's credentials were issued for, e.g. OAuth token scopes
+    Scopes []string
+}
+
+// OperationRule is what a principal needs to call one operation
+type OperationRule struct {
+    // Permission must be granted by one of the principal's roles
+    Permission string `json:"permission"`
+    // Scopes must all be held by the principal
+    Scopes []string `json:"scopes,omitempty"`
+}
+
+// Policy maps roles to permissions and operations to their rules. It denies
+// by default: an operation with no rule is never allowed.
+type Policy struct {
+    Roles      map[string][]string      `json:"roles"`
+    Operations map[string]OperationRule `json:"operations"`
+}
+
+// LoadPolicy reads a JSON policy file
+func LoadPolicy(path string) (*Policy, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    policy, err := ParsePolicy(f)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return policy, nil
+}
+
+// ParsePolicy reads a JSON policy. Unknown fields are rejected so a typo cannot
+// silently drop a rule.
+func ParsePolicy(r io.Reader) (*Policy, error) {
+    decoder := json.NewDecoder(r)
+    decoder.DisallowUnknownFields()
+    var policy Policy
+    if err := decoder.Decode(&policy); err != nil {
+        return nil, fmt.Errorf("proxy: parse policy: %w", err)
+    }
+    for name, rule := range policy.Operations {
+        if rule.Permission == "" {
+            return nil, fmt.Errorf("proxy: operation %q has no permission", name)
+        }
+    }
+    return &policy, nil
+}
+
+// Authorize decides whether principal may call operation. It returns nil and
+// the granting role when allowed, or an *AccessDeniedError.
+func (p *Policy) Authorize(principal Principal, operation string) (role string, err error) {
+    deny := func(format string, args ...any) (string, error) {
+        return "", &AccessDeniedError{Principal: principal.ID, Operation: operation, Reason: fmt.Sprintf(format, args...)}
+    }
+
+    if p == nil {
+        return deny("no policy")
+    }
+    rule, ok := p.Operations[operation]
+    if !ok {
+        return deny("no rule for operation")
+    }
+    for _, scope := range rule.Scopes {
+        if !slices.Contains(principal.Scopes, scope) {
+            return deny("missing scope %q", scope)
+        }
+    }
+    for _, role := range principal.Roles {
+        permissions := p.Roles[role]
+        if slices.Contains(permissions, rule.Permission) || slices.Contains(permissions, WildcardPermission) {
+            return role, nil
+        }
+    }
+    return deny("no role grants %q", rule.Permission)
+}
+
+// AuditEvent records one authorization decision
+type AuditEvent struct {
+    Time      time.Time `json:"time"`
+    Principal string    `json:"principal"`
+    Operation string    `json:"operation"`
+    Allowed   bool      `json:"allowed"`
+    // Role is the role that granted access, empty when denied
+    Role   string `json:"role,omitempty"`
+    Reason string `json:"reason,omitempty"`
+}
+
+// AuditSink receives every decision a ProtectionProxy makes. A decision that
+// cannot be recorded fails the call, so nothing is allowed without a trace.
+type AuditSink interface {
+    Record(event AuditEvent) error
+}
+
+// AuditSinkFunc adapts a function to the AuditSink interface
+type AuditSinkFunc func(event AuditEvent) error
+
+// Record implements the AuditSink interface
+func (f AuditSinkFunc) Record(event AuditEvent) error {
+    return f(event)
+}
+
+// JSONAuditSink writes each decision as one line of JSON
+type JSONAuditSink struct {
+    mu      sync.Mutex
+    encoder *json.Encoder
+}
+
+// NewJSONAuditSink creates a new JSONAuditSink writing to w
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+    return &JSONAuditSink{encoder: json.NewEncoder(w)}
+}
+
+// Record implements the AuditSink interface
+func (s *JSONAuditSink) Record(event AuditEvent) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err := s.encoder.Encode(event); err != nil {
+        return fmt.Errorf("proxy: writing audit event: %w", err)
+    }
+    return nil
+}
+
+// ProtectionProxy controls access to a Subject based on a policy
+type ProtectionProxy struct {
+    subject   Subject
+    principal Principal
+    policy    *Policy
+    audit     AuditSink
+    now       func() time.Time
+}
+
+// NewProtectionProxy creates a new ProtectionProxy that calls subject on
+// behalf of principal. A nil audit sink discards decisions.
+func NewProtectionProxy(subject Subject, principal Principal, policy *Policy, audit AuditSink) *ProtectionProxy {
+    if audit == nil {
+        audit = AuditSinkFunc(func(AuditEvent) error { return nil })
+    }
+    return &ProtectionProxy{
+        subject:   subject,
+        principal: principal,
+        policy:    policy,
+        audit:     audit,
+        now:       time.Now,
+    }
+}
+
+// Call checks the policy and forwards the request. It returns an
+// *AccessDeniedError when the policy denies the call, or the audit sink's
+// error when the decision could not be recorded.
+func (p *ProtectionProxy) Call() (string, error) {
+    if err := p.authorize(OperationRequest); err != nil {
+        return "", err
+    }
+    return p.subject.Request(), nil
+}
+
+// Request implements the Subject interface. A failed call returns an empty
+// string; the denial is in the audit log, and Call returns it as an error.
+func (p *ProtectionProxy) Request() string {
+    result, _ := p.Call()
+    return result
+}
+
+// authorize checks operation against the policy and audits the decision
+func (p *ProtectionProxy) authorize(operation string) error {
+    role, err := p.policy.Authorize(p.principal, operation)
+    event := AuditEvent{
+        Time:      p.now(),
+        Principal: p.principal.ID,
+        Operation: operation,
+        Allowed:   err == nil,
+        Role:      role,
+    }
+    var denied *AccessDeniedError
+    if errors.As(err, &denied) {
+        event.Reason = denied.Reason
+    }
+    return errors.Join(err, p.audit.Record(event))
+}
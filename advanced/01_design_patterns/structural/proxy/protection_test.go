@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPolicy = `{
+    "roles": {
+        "admin": ["*"],
+        "reader": ["subject.read"],
+        "auditor": ["audit.read"]
+    },
+    "operations": {
+        "request": {"permission": "subject.read", "scopes": ["subject"]}
+    }
+}`
+
+// memoryAuditSink keeps every decision for inspection
+type memoryAuditSink struct {
+    events []AuditEvent
+}
+
+func (s *memoryAuditSink) Record(event AuditEvent) error {
+    s.events = append(s.events, event)
+    return nil
+}
+
+// failingWriter rejects every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+    return 0, errors.New("disk full")
+}
+
+// taggedSubject is a Subject other than RealSubject
+type taggedSubject struct {
+    inner Subject
+    tag   string
+}
+
+func (s taggedSubject) Request() string {
+    return s.tag + "(" + s.inner.Request() + ")"
+}
+
+func loadTestPolicy(t *testing.T) *Policy {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "policy.json")
+    if err := os.WriteFile(path, []byte(testPolicy), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    policy, err := LoadPolicy(path)
+    if err != nil {
+        t.Fatalf("LoadPolicy failed: %v", err)
+    }
+    return policy
+}
+
+func TestProtectionProxyPolicy(t *testing.T) {
+    policy := loadTestPolicy(t)
+    tests := []struct {
+        name      string
+        principal Principal
+        reason    string
+    }{
+        {"reader", Principal{ID: "alice", Roles: []string{"reader"}, Scopes: []string{"subject"}}, ""},
+        {"admin", Principal{ID: "root", Roles: []string{"admin"}, Scopes: []string{"subject"}}, ""},
+        {"missing scope", Principal{ID: "bob", Roles: []string{"reader"}}, `missing scope "subject"`},
+        {"wrong role", Principal{ID: "carol", Roles: []string{"auditor"}, Scopes: []string{"subject"}}, `no role grants "subject.read"`},
+        {"unknown role", Principal{ID: "dave", Roles: []string{"ghost"}, Scopes: []string{"subject"}}, `no role grants "subject.read"`},
+        {"no roles", Principal{ID: "eve", Scopes: []string{"subject"}}, `no role grants "subject.read"`},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            proxy := NewProtectionProxy(NewRealSubject("test"), tt.principal, policy, nil)
+            result, err := proxy.Call()
+            if tt.reason == "" {
+                if err != nil || result != "RealSubject test request" {
+                    t.Errorf("Expected access, got '%s', %v", result, err)
+                }
+                return
+            }
+            var denied *AccessDeniedError
+            if !errors.As(err, &denied) || !errors.Is(err, ErrAccessDenied) {
+                t.Fatalf("Expected *AccessDeniedError, got %v", err)
+            }
+            if denied.Reason != tt.reason || denied.Principal != tt.principal.ID || denied.Operation != OperationRequest {
+                t.Errorf("Unexpected denial %+v", denied)
+            }
+        })
+    }
+}
+
+func TestPolicyDeniesByDefault(t *testing.T) {
+    policy := loadTestPolicy(t)
+    admin := Principal{ID: "root", Roles: []string{"admin"}}
+    if _, err := policy.Authorize(admin, "delete"); !errors.Is(err, ErrAccessDenied) {
+        t.Errorf("Expected an operation without a rule to be denied, got %v", err)
+    }
+
+    var nilPolicy *Policy
+    if _, err := nilPolicy.Authorize(admin, OperationRequest); !errors.Is(err, ErrAccessDenied) {
+        t.Errorf("Expected a nil policy to deny, got %v", err)
+    }
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+    tests := []struct {
+        name   string
+        policy string
+    }{
+        {"malformed", `{"roles": `},
+        {"unknown field", `{"roles": {}, "operation": {}}`},
+        {"missing permission", `{"operations": {"request": {"scopes": ["subject"]}}}`},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if _, err := ParsePolicy(strings.NewReader(tt.policy)); err == nil {
+                t.Error("Expected an error")
+            }
+        })
+    }
+    if _, err := LoadPolicy(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+        t.Errorf("Expected os.ErrNotExist, got %v", err)
+    }
+}
+
+func TestProtectionProxyAudit(t *testing.T) {
+    policy := loadTestPolicy(t)
+    sink := &memoryAuditSink{}
+    subject := NewRealSubject("test")
+
+    NewProtectionProxy(subject, Principal{ID: "alice", Roles: []string{"reader"}, Scopes: []string{"subject"}}, policy, sink).Call()
+    NewProtectionProxy(subject, Principal{ID: "bob", Roles: []string{"reader"}}, policy, sink).Call()
+
+    if len(sink.events) != 2 {
+        t.Fatalf("Expected 2 audit events, got %d", len(sink.events))
+    }
+    allowed, denied := sink.events[0], sink.events[1]
+    if !allowed.Allowed || allowed.Principal != "alice" || allowed.Role != "reader" || allowed.Time.IsZero() {
+        t.Errorf("Unexpected allow event %+v", allowed)
+    }
+    if denied.Allowed || denied.Principal != "bob" || denied.Reason != `missing scope "subject"` {
+        t.Errorf("Unexpected deny event %+v", denied)
+    }
+}
+
+func TestJSONAuditSink(t *testing.T) {
+    var buf bytes.Buffer
+    sink := NewJSONAuditSink(&buf)
+    sink.Record(AuditEvent{Principal: "alice", Operation: OperationRequest, Allowed: true, Role: "reader"})
+    sink.Record(AuditEvent{Principal: "bob", Operation: OperationRequest, Reason: "missing scope"})
+
+    lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+    if len(lines) != 2 {
+        t.Fatalf("Expected 2 lines, got %d", len(lines))
+    }
+    var event AuditEvent
+    if err := json.Unmarshal([]byte(lines[1]), &event); err != nil || event.Principal != "bob" || event.Allowed {
+        t.Errorf("Unexpected event %+v, %v", event, err)
+    }
+}
+
+func TestProtectionProxyRequestHidesDenial(t *testing.T) {
+    sink := &memoryAuditSink{}
+    proxy := NewProtectionProxy(NewRealSubject("test"), Principal{ID: "bob"}, loadTestPolicy(t), sink)
+    if result := proxy.Request(); result != "" {
+        t.Errorf("Expected an empty result for a denied call, got '%s'", result)
+    }
+    if len(sink.events) != 1 || sink.events[0].Allowed {
+        t.Errorf("Expected the denial to be audited, got %+v", sink.events)
+    }
+}
+
+func TestProtectionProxyWrapsAnySubject(t *testing.T) {
+    policy := loadTestPolicy(t)
+    alice := Principal{ID: "alice", Roles: []string{"reader"}, Scopes: []string{"subject"}}
+    inner := NewProtectionProxy(NewRealSubject("test"), alice, policy, nil)
+    outer := NewProtectionProxy(taggedSubject{inner: inner, tag: "tagged"}, alice, policy, nil)
+
+    if result, err := outer.Call(); err != nil || result != "tagged(RealSubject test request)" {
+        t.Errorf("Unexpected result '%s', %v", result, err)
+    }
+}
+
+func TestProtectionProxyAuditFailure(t *testing.T) {
+    alice := Principal{ID: "alice", Roles: []string{"reader"}, Scopes: []string{"subject"}}
+    proxy := NewProtectionProxy(NewRealSubject("test"), alice, loadTestPolicy(t), NewJSONAuditSink(failingWriter{}))
+
+    result, err := proxy.Call()
+    if err == nil || !strings.Contains(err.Error(), "disk full") || result != "" {
+        t.Errorf("Expected an allowed call to fail when it cannot be audited, got '%s', %v", result, err)
+    }
+}
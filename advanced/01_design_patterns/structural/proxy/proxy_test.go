@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -31,19 +32,22 @@ func TestProxy(t *testing.T) {
 
 func TestProtectionProxy(t *testing.T) {
     realSubject := NewRealSubject("test")
+    policy := &Policy{
+        Roles:      map[string][]string{"admin": {WildcardPermission}},
+        Operations: map[string]OperationRule{OperationRequest: {Permission: "subject.read"}},
+    }
     
-    // Test with admin permission
-    adminProxy := NewProtectionProxy(realSubject, "admin")
+    // Test with admin role
+    adminProxy := NewProtectionProxy(realSubject, Principal{ID: "alice", Roles: []string{"admin"}}, policy, nil)
     expected := "RealSubject test request"
     if result := adminProxy.Request(); result != expected {
         t.Errorf("Expected '%s', got '%s'", expected, result)
     }
     
-    // Test with non-admin permission
-    userProxy := NewProtectionProxy(realSubject, "user")
-    expected = "Access denied"
-    if result := userProxy.Request(); result != expected {
-        t.Errorf("Expected '%s', got '%s'", expected, result)
+    // Test with non-admin role
+    userProxy := NewProtectionProxy(realSubject, Principal{ID: "bob", Roles: []string{"user"}}, policy, nil)
+    if _, err := userProxy.Call(); !errors.Is(err, ErrAccessDenied) {
+        t.Errorf("Expected ErrAccessDenied, got %v", err)
     }
 }
 
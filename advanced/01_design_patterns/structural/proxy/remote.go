@@ -0,0 +1,255 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// MethodRequest is the JSON-RPC method that calls Subject.Request
+const MethodRequest = "Subject.Request"
+
+// JSON-RPC 2.0 error codes
+const (
+    CodeParseError     = -32700
+    CodeInvalidRequest = -32600
+    CodeMethodNotFound = -32601
+    CodeInvalidParams  = -32602
+    CodeInternalError  = -32603
+)
+
+// ErrRemoteUnavailable is returned when the remote subject cannot be reached or answers with a server error
+var ErrRemoteUnavailable = errors.New("proxy: remote subject unavailable")
+
+// ErrRemoteTimeout is returned when an attempt takes longer than RemoteConfig.Timeout
+var ErrRemoteTimeout = errors.New("proxy: remote call timed out")
+
+// ErrBadResponse is returned when the server's reply is not a valid JSON-RPC response
+var ErrBadResponse = errors.New("proxy: bad remote response")
+
+// RPCError is a JSON-RPC error object, returned by the server and surfaced by RemoteProxy
+type RPCError struct {
+    Code    int    `json:"code"`
+    Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+    return fmt.Sprintf("proxy: rpc error %d: %s", e.Code, e.Message)
+}
+
+type rpcRequest struct {
+    JSONRPC string          `json:"jsonrpc"`
+    Method  string          `json:"method"`
+    Params  json.RawMessage `json:"params,omitempty"`
+    ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type rpcResponse struct {
+    JSONRPC string          `json:"jsonrpc"`
+    Result  json.RawMessage `json:"result,omitempty"`
+    Error   *RPCError       `json:"error,omitempty"`
+    ID      json.RawMessage `json:"id"`
+}
+
+// SubjectHandler publishes a Subject over HTTP as a JSON-RPC 2.0 service.
+// Requests are POSTed one at a time; batches are not supported.
+type SubjectHandler struct {
+    subject Subject
+}
+
+// NewSubjectHandler creates a new SubjectHandler for subject
+func NewSubjectHandler(subject Subject) *SubjectHandler {
+    return &SubjectHandler{subject: subject}
+}
+
+// ServeHTTP implements the http.Handler interface
+func (h *SubjectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        w.Header().Set("Allow", http.MethodPost)
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    var request rpcRequest
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        writeResponse(w, rpcResponse{Error: &RPCError{Code: CodeParseError, Message: err.Error()}, ID: json.RawMessage("null")})
+        return
+    }
+    id := request.ID
+    if id == nil {
+        id = json.RawMessage("null")
+    }
+    if request.JSONRPC != "2.0" || request.Method == "" {
+        writeResponse(w, rpcResponse{Error: &RPCError{Code: CodeInvalidRequest, Message: "invalid request"}, ID: id})
+        return
+    }
+
+    result, rpcErr := h.call(request)
+    // A request without an id is a notification and gets no response
+    if request.ID == nil {
+        w.WriteHeader(http.StatusNoContent)
+        return
+    }
+    if rpcErr != nil {
+        writeResponse(w, rpcResponse{Error: rpcErr, ID: id})
+        return
+    }
+    writeResponse(w, rpcResponse{Result: result, ID: id})
+}
+
+// call dispatches a request to the subject, turning a panic into an internal error
+func (h *SubjectHandler) call(request rpcRequest) (result json.RawMessage, rpcErr *RPCError) {
+    if request.Method != MethodRequest {
+        return nil, &RPCError{Code: CodeMethodNotFound, Message: "method not found: " + request.Method}
+    }
+    if params := bytes.TrimSpace(request.Params); len(params) > 0 && !bytes.Equal(params, []byte("[]")) && !bytes.Equal(params, []byte("{}")) {
+        return nil, &RPCError{Code: CodeInvalidParams, Message: MethodRequest + " takes no params"}
+    }
+    defer func() {
+        if r := recover(); r != nil {
+            result, rpcErr = nil, &RPCError{Code: CodeInternalError, Message: fmt.Sprint(r)}
+        }
+    }()
+    encoded, err := json.Marshal(h.subject.Request())
+    if err != nil {
+        return nil, &RPCError{Code: CodeInternalError, Message: err.Error()}
+    }
+    return encoded, nil
+}
+
+func writeResponse(w http.ResponseWriter, response rpcResponse) {
+    response.JSONRPC = "2.0"
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(response)
+}
+
+// RemoteConfig configures a RemoteProxy
+type RemoteConfig struct {
+    // Timeout bounds each attempt. Zero means no per-attempt timeout.
+    Timeout time.Duration
+    // MaxAttempts is the total number of attempts, including the first. Zero or one means no retries.
+    MaxAttempts int
+    // BaseDelay is the wait before the first retry. It doubles with each further retry.
+    BaseDelay time.Duration
+    // HTTPClient sends the requests. Nil means http.DefaultClient.
+    HTTPClient *http.Client
+}
+
+// RemoteProxy implements Subject by calling a SubjectHandler over HTTP.
+// Unavailable servers and timed out attempts are retried; JSON-RPC errors are not.
+type RemoteProxy struct {
+    endpoint string
+    config   RemoteConfig
+    client   *http.Client
+    nextID   atomic.Int64
+}
+
+// NewRemoteProxy creates a new RemoteProxy for the service at endpoint
+func NewRemoteProxy(endpoint string, config RemoteConfig) *RemoteProxy {
+    client := config.HTTPClient
+    if client == nil {
+        client = http.DefaultClient
+    }
+    return &RemoteProxy{endpoint: endpoint, config: config, client: client}
+}
+
+// Call invokes Request on the remote subject. Errors wrap ErrRemoteUnavailable,
+// ErrRemoteTimeout or ErrBadResponse, or are an *RPCError from the server.
+func (p *RemoteProxy) Call(ctx context.Context) (string, error) {
+    attempts := max(p.config.MaxAttempts, 1)
+    delay := p.config.BaseDelay
+    var err error
+    for attempt := 1; ; attempt++ {
+        var result json.RawMessage
+        result, err = p.attempt(ctx)
+        if err == nil {
+            var value string
+            if err := json.Unmarshal(result, &value); err != nil {
+                return "", fmt.Errorf("%w: %w", ErrBadResponse, err)
+            }
+            return value, nil
+        }
+        if attempt >= attempts || !(errors.Is(err, ErrRemoteUnavailable) || errors.Is(err, ErrRemoteTimeout)) {
+            return "", err
+        }
+
+        timer := time.NewTimer(delay)
+        select {
+        case <-timer.C:
+        case <-ctx.Done():
+            timer.Stop()
+            return "", errors.Join(err, ctx.Err())
+        }
+        delay *= 2
+    }
+}
+
+// Request implements the Subject interface. A failed call returns an empty
+// string; use Call to get the error.
+func (p *RemoteProxy) Request() string {
+    result, _ := p.Call(context.Background())
+    return result
+}
+
+// attempt makes one HTTP round trip and returns the JSON-RPC result
+func (p *RemoteProxy) attempt(ctx context.Context) (json.RawMessage, error) {
+    attemptCtx := ctx
+    if p.config.Timeout > 0 {
+        var cancel context.CancelFunc
+        attemptCtx, cancel = context.WithTimeout(ctx, p.config.Timeout)
+        defer cancel()
+    }
+
+    id := p.nextID.Add(1)
+    body, err := json.Marshal(rpcRequest{JSONRPC: "2.0", Method: MethodRequest, ID: json.RawMessage(fmt.Sprint(id))})
+    if err != nil {
+        return nil, err
+    }
+    req, err := http.NewRequestWithContext(attemptCtx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := p.client.Do(req)
+    if err != nil {
+        // Only the attempt's own deadline is a timeout; the caller's cancellation is returned as is
+        if ctx.Err() == nil && attemptCtx.Err() != nil {
+            return nil, fmt.Errorf("%w after %s", ErrRemoteTimeout, p.config.Timeout)
+        }
+        if ctx.Err() != nil {
+            return nil, ctx.Err()
+        }
+        return nil, fmt.Errorf("%w: %w", ErrRemoteUnavailable, err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode >= 500 {
+        io.Copy(io.Discard, resp.Body)
+        return nil, fmt.Errorf("%w: %s", ErrRemoteUnavailable, resp.Status)
+    }
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("%w: %s", ErrBadResponse, resp.Status)
+    }
+
+    var response rpcResponse
+    if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+        if ctx.Err() == nil && attemptCtx.Err() != nil {
+            return nil, fmt.Errorf("%w after %s", ErrRemoteTimeout, p.config.Timeout)
+        }
+        return nil, fmt.Errorf("%w: %w", ErrBadResponse, err)
+    }
+    if response.JSONRPC != "2.0" || string(response.ID) != fmt.Sprint(id) {
+        return nil, fmt.Errorf("%w: unexpected version %q or id %s", ErrBadResponse, response.JSONRPC, response.ID)
+    }
+    if response.Error != nil {
+        return nil, response.Error
+    }
+    return response.Result, nil
+}
@@ -0,0 +1,201 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// panicSubject is a Subject whose Request always panics
+type panicSubject struct{}
+
+func (panicSubject) Request() string {
+    panic("boom")
+}
+
+func postRPC(t *testing.T, url, body string) (int, rpcResponse) {
+    t.Helper()
+    resp, err := http.Post(url, "application/json", strings.NewReader(body))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+    var response rpcResponse
+    if resp.StatusCode == http.StatusOK {
+        if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return resp.StatusCode, response
+}
+
+func TestRemoteProxy(t *testing.T) {
+    server := httptest.NewServer(NewSubjectHandler(NewRealSubject("remote")))
+    defer server.Close()
+
+    proxy := NewRemoteProxy(server.URL, RemoteConfig{Timeout: time.Second})
+    client := NewClient(proxy)
+    expected := "RealSubject remote request"
+    if result := client.UseSubject(); result != expected {
+        t.Errorf("Expected '%s', got '%s'", expected, result)
+    }
+}
+
+func TestSubjectHandlerProtocol(t *testing.T) {
+    server := httptest.NewServer(NewSubjectHandler(NewRealSubject("remote")))
+    defer server.Close()
+
+    tests := []struct {
+        name string
+        body string
+        code int
+    }{
+        {"parse error", `{"jsonrpc":`, CodeParseError},
+        {"wrong version", `{"jsonrpc":"1.0","method":"Subject.Request","id":1}`, CodeInvalidRequest},
+        {"missing method", `{"jsonrpc":"2.0","id":1}`, CodeInvalidRequest},
+        {"unknown method", `{"jsonrpc":"2.0","method":"Subject.Delete","id":1}`, CodeMethodNotFound},
+        {"unexpected params", `{"jsonrpc":"2.0","method":"Subject.Request","params":["x"],"id":1}`, CodeInvalidParams},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            status, response := postRPC(t, server.URL, tt.body)
+            if status != http.StatusOK || response.Error == nil || response.Error.Code != tt.code {
+                t.Errorf("Expected error code %d, got status %d and %+v", tt.code, status, response.Error)
+            }
+        })
+    }
+
+    status, response := postRPC(t, server.URL, `{"jsonrpc":"2.0","method":"Subject.Request","id":"abc"}`)
+    if status != http.StatusOK || string(response.ID) != `"abc"` || string(response.Result) != `"RealSubject remote request"` {
+        t.Errorf("Unexpected response %d %+v", status, response)
+    }
+
+    // Notifications get no response body
+    if status, _ := postRPC(t, server.URL, `{"jsonrpc":"2.0","method":"Subject.Request"}`); status != http.StatusNoContent {
+        t.Errorf("Expected 204 for a notification, got %d", status)
+    }
+
+    resp, err := http.Get(server.URL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusMethodNotAllowed {
+        t.Errorf("Expected 405 for GET, got %d", resp.StatusCode)
+    }
+}
+
+func TestRemoteProxyMapsRPCErrors(t *testing.T) {
+    server := httptest.NewServer(NewSubjectHandler(panicSubject{}))
+    defer server.Close()
+
+    var calls atomic.Int64
+    proxy := NewRemoteProxy(server.URL, RemoteConfig{
+        MaxAttempts: 3,
+        HTTPClient:  &http.Client{Transport: countingTransport{&calls}},
+    })
+    _, err := proxy.Call(context.Background())
+
+    var rpcErr *RPCError
+    if !errors.As(err, &rpcErr) || rpcErr.Code != CodeInternalError || rpcErr.Message != "boom" {
+        t.Fatalf("Expected an internal RPCError, got %v", err)
+    }
+    if calls.Load() != 1 {
+        t.Errorf("Expected RPC errors not to be retried, got %d attempts", calls.Load())
+    }
+}
+
+// countingTransport counts round trips before passing them to the default transport
+type countingTransport struct {
+    calls *atomic.Int64
+}
+
+func (c countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    c.calls.Add(1)
+    return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRemoteProxyRetriesUnavailable(t *testing.T) {
+    var calls atomic.Int64
+    handler := NewSubjectHandler(NewRealSubject("remote"))
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if calls.Add(1) <= 2 {
+            http.Error(w, "unavailable", http.StatusServiceUnavailable)
+            return
+        }
+        handler.ServeHTTP(w, r)
+    }))
+    defer server.Close()
+
+    proxy := NewRemoteProxy(server.URL, RemoteConfig{MaxAttempts: 3, BaseDelay: time.Millisecond})
+    if result, err := proxy.Call(context.Background()); err != nil || result != "RealSubject remote request" {
+        t.Errorf("Expected success on the third attempt, got '%s', %v", result, err)
+    }
+
+    calls.Store(0)
+    proxy = NewRemoteProxy(server.URL, RemoteConfig{MaxAttempts: 2, BaseDelay: time.Millisecond})
+    if _, err := proxy.Call(context.Background()); !errors.Is(err, ErrRemoteUnavailable) {
+        t.Errorf("Expected ErrRemoteUnavailable after 2 attempts, got %v", err)
+    }
+}
+
+func TestRemoteProxyTimeout(t *testing.T) {
+    release := make(chan struct{})
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        select {
+        case <-release:
+        case <-r.Context().Done():
+        }
+    }))
+    defer server.Close()
+    defer close(release)
+
+    proxy := NewRemoteProxy(server.URL, RemoteConfig{Timeout: 20 * time.Millisecond, MaxAttempts: 2, BaseDelay: time.Millisecond})
+    start := time.Now()
+    if _, err := proxy.Call(context.Background()); !errors.Is(err, ErrRemoteTimeout) {
+        t.Errorf("Expected ErrRemoteTimeout, got %v", err)
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Errorf("Expected the timeout to bound each attempt, took %s", elapsed)
+    }
+
+    // The caller's own cancellation is not reported as a timeout
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    proxy = NewRemoteProxy(server.URL, RemoteConfig{Timeout: time.Second})
+    if _, err := proxy.Call(ctx); !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrRemoteTimeout) {
+        t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+    }
+}
+
+func TestRemoteProxyBadResponse(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(`{"jsonrpc":"2.0","result":"x","id":999}`))
+    }))
+    defer server.Close()
+
+    proxy := NewRemoteProxy(server.URL, RemoteConfig{MaxAttempts: 3})
+    if _, err := proxy.Call(context.Background()); !errors.Is(err, ErrBadResponse) {
+        t.Errorf("Expected ErrBadResponse for a mismatched id, got %v", err)
+    }
+}
+
+func TestRemoteProxyUnreachable(t *testing.T) {
+    server := httptest.NewServer(http.NotFoundHandler())
+    url := server.URL
+    server.Close()
+
+    proxy := NewRemoteProxy(url, RemoteConfig{MaxAttempts: 2, BaseDelay: time.Millisecond})
+    if _, err := proxy.Call(context.Background()); !errors.Is(err, ErrRemoteUnavailable) {
+        t.Errorf("Expected ErrRemoteUnavailable, got %v", err)
+    }
+    if result := proxy.Request(); result != "" {
+        t.Errorf("Expected an empty result from a failed call, got '%s'", result)
+    }
+}
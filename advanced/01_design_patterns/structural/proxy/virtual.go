@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrInitBackoff is returned, wrapping the last failure, when a Lazy is waiting
+// before its next initialization attempt
+var ErrInitBackoff = errors.New("proxy: initialization backing off")
+
+// LazyConfig configures the retry backoff of a Lazy
+type LazyConfig struct {
+    // BaseDelay is how long to wait after the first failure. It doubles with
+    // each further failure. Zero retries on the next call.
+    BaseDelay time.Duration
+    // MaxDelay caps the backoff. Zero means no cap.
+    MaxDelay time.Duration
+    Clock    Clock
+}
+
+// Lazy creates a value on first use. At most one initialization attempt runs
+// at a time and concurrent callers wait for it. After a failed attempt, calls
+// fail fast with ErrInitBackoff until the backoff has passed. A panicking
+// loader counts as a failed attempt.
+type Lazy[T any] struct {
+    loader  func(ctx context.Context) (T, error)
+    config  LazyConfig
+    clock   Clock
+    mu      sync.Mutex
+    value   T
+    loaded  bool
+    attempt *lazyAttempt[T]
+    // failures counts consecutive failed attempts; lastErr and retryAt describe the latest one
+    failures int
+    lastErr  error
+    retryAt  time.Time
+}
+
+// lazyAttempt is one loader call that concurrent callers wait on together
+type lazyAttempt[T any] struct {
+    done  chan struct{}
+    value T
+    err   error
+}
+
+// NewLazy creates a new Lazy that initializes its value with loader
+func NewLazy[T any](loader func(ctx context.Context) (T, error), config LazyConfig) *Lazy[T] {
+    clock := config.Clock
+    if clock == nil {
+        clock = systemClock{}
+    }
+    return &Lazy[T]{loader: loader, config: config, clock: clock}
+}
+
+// Get returns the value, initializing it first if needed. The attempt runs
+// detached from ctx, so a caller that gives up does not fail it for others.
+func (l *Lazy[T]) Get(ctx context.Context) (T, error) {
+    l.mu.Lock()
+    if l.loaded {
+        l.mu.Unlock()
+        return l.value, nil
+    }
+    attempt := l.attempt
+    if attempt == nil {
+        if l.failures > 0 && l.clock.Now().Before(l.retryAt) {
+            err := fmt.Errorf("%w: %w", ErrInitBackoff, l.lastErr)
+            l.mu.Unlock()
+            var zero T
+            return zero, err
+        }
+        attempt = l.start(ctx)
+    }
+    l.mu.Unlock()
+
+    select {
+    case <-attempt.done:
+        return attempt.value, attempt.err
+    case <-ctx.Done():
+        var zero T
+        return zero, ctx.Err()
+    }
+}
+
+// Warm starts initialization in the background so the first Get does not wait.
+// It does nothing if the value is loaded, an attempt is running, or the Lazy is backing off.
+func (l *Lazy[T]) Warm(ctx context.Context) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if l.loaded || l.attempt != nil || (l.failures > 0 && l.clock.Now().Before(l.retryAt)) {
+        return
+    }
+    l.start(ctx)
+}
+
+// Reset drops the loaded value and any backoff so the next Get initializes
+// again. An attempt already running is kept, since it is already reloading.
+func (l *Lazy[T]) Reset() {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    var zero T
+    l.value, l.loaded = zero, false
+    l.failures, l.lastErr, l.retryAt = 0, nil, time.Time{}
+}
+
+// Loaded reports whether the value has been initialized
+func (l *Lazy[T]) Loaded() bool {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    return l.loaded
+}
+
+// start runs an initialization attempt. The caller holds l.mu.
+func (l *Lazy[T]) start(ctx context.Context) *lazyAttempt[T] {
+    attempt := &lazyAttempt[T]{done: make(chan struct{})}
+    l.attempt = attempt
+    go func() {
+        value, err := l.callLoader(context.WithoutCancel(ctx))
+        l.mu.Lock()
+        attempt.value, attempt.err = value, err
+        l.attempt = nil
+        if err == nil {
+            l.value, l.loaded = value, true
+            l.failures, l.lastErr = 0, nil
+        } else {
+            l.failures++
+            l.lastErr = err
+            l.retryAt = l.clock.Now().Add(l.backoff())
+        }
+        l.mu.Unlock()
+        close(attempt.done)
+    }()
+    return attempt
+}
+
+// callLoader runs the loader, turning a panic into the attempt's error so it
+// backs off like any other failure and the waiters are always released
+func (l *Lazy[T]) callLoader(ctx context.Context) (value T, err error) {
+    defer func() {
+        if r := recover(); r != nil {
+            var zero T
+            value, err = zero, fmt.Errorf("proxy: loader panicked: %v", r)
+        }
+    }()
+    return l.loader(ctx)
+}
+
+// backoff returns the wait after the current run of failures. The caller holds l.mu.
+func (l *Lazy[T]) backoff() time.Duration {
+    delay := l.config.BaseDelay
+    for i := 1; i < l.failures && delay > 0 && delay <= math.MaxInt64/2; i++ {
+        if l.config.MaxDelay > 0 && delay >= l.config.MaxDelay {
+            break
+        }
+        delay *= 2
+    }
+    if l.config.MaxDelay > 0 && delay > l.config.MaxDelay {
+        delay = l.config.MaxDelay
+    }
+    return delay
+}
+
+// VirtualProxy controls access to a resource that is expensive to create
+type VirtualProxy struct {
+    lazy *Lazy[*RealSubject]
+}
+
+// NewVirtualProxy creates a new VirtualProxy whose RealSubject is created on first use
+func NewVirtualProxy() *VirtualProxy {
+    return NewVirtualProxyWithLoader(func(ctx context.Context) (*RealSubject, error) {
+        return NewRealSubject("virtual"), nil
+    }, LazyConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: 10 * time.Second})
+}
+
+// NewVirtualProxyWithLoader creates a new VirtualProxy that creates its RealSubject with loader
+func NewVirtualProxyWithLoader(loader func(ctx context.Context) (*RealSubject, error), config LazyConfig) *VirtualProxy {
+    return &VirtualProxy{lazy: NewLazy(loader, config)}
+}
+
+// Call initializes the RealSubject if needed and forwards the request
+func (p *VirtualProxy) Call(ctx context.Context) (string, error) {
+    subject, err := p.lazy.Get(ctx)
+    if err != nil {
+        return "", err
+    }
+    return subject.Request(), nil
+}
+
+// Request implements the Subject interface. A failed initialization returns
+// an empty string; use Call to get the error.
+func (p *VirtualProxy) Request() string {
+    result, _ := p.Call(context.Background())
+    return result
+}
+
+// Warm starts creating the RealSubject in the background
+func (p *VirtualProxy) Warm() {
+    p.lazy.Warm(context.Background())
+}
+
+// Reset drops the RealSubject so the next request creates a new one
+func (p *VirtualProxy) Reset() {
+    p.lazy.Reset()
+}
@@ -0,0 +1,266 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLazyConcurrentFirstCalls(t *testing.T) {
+    release := make(chan struct{})
+    var calls atomic.Int64
+    lazy := NewLazy(func(ctx context.Context) (*RealSubject, error) {
+        calls.Add(1)
+        <-release
+        return NewRealSubject("lazy"), nil
+    }, LazyConfig{})
+
+    var wg sync.WaitGroup
+    subjects := make([]*RealSubject, 50)
+    for i := range subjects {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            subject, err := lazy.Get(context.Background())
+            if err != nil {
+                t.Errorf("Unexpected error %v", err)
+            }
+            subjects[i] = subject
+        }()
+    }
+    time.Sleep(10 * time.Millisecond)
+    close(release)
+    wg.Wait()
+
+    if calls.Load() != 1 {
+        t.Errorf("Expected one initialization, got %d", calls.Load())
+    }
+    for _, subject := range subjects {
+        if subject != subjects[0] {
+            t.Fatal("Expected every caller to get the same RealSubject")
+        }
+    }
+    if !lazy.Loaded() {
+        t.Error("Expected the value to be loaded")
+    }
+}
+
+func TestLazyRetryBackoff(t *testing.T) {
+    clock := newFakeClock()
+    errDown := errors.New("down")
+    var calls atomic.Int64
+    fail := atomic.Bool{}
+    fail.Store(true)
+    lazy := NewLazy(func(ctx context.Context) (int, error) {
+        calls.Add(1)
+        if fail.Load() {
+            return 0, errDown
+        }
+        return 42, nil
+    }, LazyConfig{BaseDelay: time.Second, MaxDelay: 3 * time.Second, Clock: clock})
+    ctx := context.Background()
+
+    if _, err := lazy.Get(ctx); !errors.Is(err, errDown) {
+        t.Fatalf("Expected errDown, got %v", err)
+    }
+    // Within the backoff calls fail fast without running the loader
+    if _, err := lazy.Get(ctx); !errors.Is(err, ErrInitBackoff) || !errors.Is(err, errDown) {
+        t.Fatalf("Expected ErrInitBackoff wrapping errDown, got %v", err)
+    }
+    if calls.Load() != 1 {
+        t.Fatalf("Expected one attempt, got %d", calls.Load())
+    }
+
+    // The delay doubles after each failure: 1s, 2s, then capped at 3s
+    for _, delay := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+        clock.Advance(delay - time.Millisecond)
+        if _, err := lazy.Get(ctx); !errors.Is(err, ErrInitBackoff) {
+            t.Fatalf("Expected backoff before %s, got %v", delay, err)
+        }
+        clock.Advance(time.Millisecond)
+        if _, err := lazy.Get(ctx); !errors.Is(err, errDown) || errors.Is(err, ErrInitBackoff) {
+            t.Fatalf("Expected a new attempt after %s, got %v", delay, err)
+        }
+    }
+
+    fail.Store(false)
+    clock.Advance(3 * time.Second)
+    if value, err := lazy.Get(ctx); value != 42 || err != nil {
+        t.Errorf("Expected recovery, got %d, %v", value, err)
+    }
+    if calls.Load() != 5 {
+        t.Errorf("Expected 5 attempts, got %d", calls.Load())
+    }
+}
+
+func TestLazyLoaderPanic(t *testing.T) {
+    clock := newFakeClock()
+    var calls atomic.Int64
+    lazy := NewLazy(func(ctx context.Context) (int, error) {
+        calls.Add(1)
+        panic("boom")
+    }, LazyConfig{BaseDelay: time.Second, Clock: clock})
+    ctx := context.Background()
+
+    if _, err := lazy.Get(ctx); err == nil || !strings.Contains(err.Error(), "boom") {
+        t.Fatalf("Expected the panic as an error, got %v", err)
+    }
+    // The panic counts toward the backoff like any other failure
+    if _, err := lazy.Get(ctx); !errors.Is(err, ErrInitBackoff) {
+        t.Errorf("Expected ErrInitBackoff after a panic, got %v", err)
+    }
+    clock.Advance(time.Second)
+    if _, err := lazy.Get(ctx); err == nil || errors.Is(err, ErrInitBackoff) {
+        t.Errorf("Expected a new attempt after the backoff, got %v", err)
+    }
+    if calls.Load() != 2 || lazy.Loaded() {
+        t.Errorf("Expected 2 attempts and nothing loaded, got %d", calls.Load())
+    }
+}
+
+func TestLazyWarm(t *testing.T) {
+    loaded := make(chan struct{})
+    lazy := NewLazy(func(ctx context.Context) (string, error) {
+        defer close(loaded)
+        return "warm", nil
+    }, LazyConfig{})
+
+    lazy.Warm(context.Background())
+    <-loaded
+    for !lazy.Loaded() {
+        time.Sleep(time.Millisecond)
+    }
+    // A second Warm does not start another attempt, which would close loaded twice
+    lazy.Warm(context.Background())
+    if value, _ := lazy.Get(context.Background()); value != "warm" {
+        t.Errorf("Expected 'warm', got '%s'", value)
+    }
+}
+
+func TestLazyReset(t *testing.T) {
+    var calls atomic.Int64
+    lazy := NewLazy(func(ctx context.Context) (int64, error) {
+        return calls.Add(1), nil
+    }, LazyConfig{})
+
+    first, _ := lazy.Get(context.Background())
+    lazy.Reset()
+    if lazy.Loaded() {
+        t.Error("Expected Reset to drop the value")
+    }
+    second, _ := lazy.Get(context.Background())
+    if first != 1 || second != 2 {
+        t.Errorf("Expected a reload after Reset, got %d and %d", first, second)
+    }
+}
+
+func TestLazyResetClearsBackoff(t *testing.T) {
+    lazy := NewLazy(func(ctx context.Context) (int, error) {
+        return 0, errors.New("down")
+    }, LazyConfig{BaseDelay: time.Hour, Clock: newFakeClock()})
+
+    lazy.Get(context.Background())
+    lazy.Reset()
+    if _, err := lazy.Get(context.Background()); errors.Is(err, ErrInitBackoff) {
+        t.Error("Expected Reset to allow an immediate retry")
+    }
+}
+
+func TestLazyCallerCancellation(t *testing.T) {
+    release := make(chan struct{})
+    lazy := NewLazy(func(ctx context.Context) (string, error) {
+        <-release
+        return "value", ctx.Err()
+    }, LazyConfig{})
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if _, err := lazy.Get(ctx); !errors.Is(err, context.Canceled) {
+        t.Fatalf("Expected context.Canceled, got %v", err)
+    }
+    close(release)
+    if value, err := lazy.Get(context.Background()); value != "value" || err != nil {
+        t.Errorf("Expected the attempt to finish for other callers, got '%s', %v", value, err)
+    }
+}
+
+func TestLazyOneAttemptAtATime(t *testing.T) {
+    var running, overlaps atomic.Int64
+    lazy := NewLazy(func(ctx context.Context) (int, error) {
+        if running.Add(1) > 1 {
+            overlaps.Add(1)
+        }
+        time.Sleep(time.Millisecond)
+        running.Add(-1)
+        return 1, nil
+    }, LazyConfig{})
+
+    // Hammer Get, Warm and Reset together; run with -race
+    var wg sync.WaitGroup
+    for g := 0; g < 8; g++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := 0; i < 50; i++ {
+                switch i % 3 {
+                case 0:
+                    lazy.Get(context.Background())
+                case 1:
+                    lazy.Warm(context.Background())
+                case 2:
+                    lazy.Reset()
+                }
+            }
+        }()
+    }
+    wg.Wait()
+    if overlaps.Load() != 0 {
+        t.Errorf("Expected attempts never to overlap, got %d overlaps", overlaps.Load())
+    }
+}
+
+func TestVirtualProxyConcurrent(t *testing.T) {
+    var created atomic.Int64
+    proxy := NewVirtualProxyWithLoader(func(ctx context.Context) (*RealSubject, error) {
+        created.Add(1)
+        return NewRealSubject("virtual"), nil
+    }, LazyConfig{})
+
+    var wg sync.WaitGroup
+    for i := 0; i < 20; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if result := proxy.Request(); result != "RealSubject virtual request" {
+                t.Errorf("Unexpected result '%s'", result)
+            }
+        }()
+    }
+    wg.Wait()
+    if created.Load() != 1 {
+        t.Errorf("Expected one RealSubject, got %d", created.Load())
+    }
+
+    proxy.Reset()
+    proxy.Request()
+    if created.Load() != 2 {
+        t.Errorf("Expected Reset to create a new RealSubject, got %d", created.Load())
+    }
+}
+
+func TestVirtualProxyInitError(t *testing.T) {
+    errDown := errors.New("down")
+    proxy := NewVirtualProxyWithLoader(func(ctx context.Context) (*RealSubject, error) {
+        return nil, errDown
+    }, LazyConfig{})
+    if _, err := proxy.Call(context.Background()); !errors.Is(err, errDown) {
+        t.Errorf("Expected errDown, got %v", err)
+    }
+    if result := proxy.Request(); result != "" {
+        t.Errorf("Expected an empty result after a failed initialization, got '%s'", result)
+    }
+}